@@ -53,3 +53,42 @@ resource "hiveio_virtual_machine" "kubuntu" {
 }
 
 
+
+resource "hiveio_virtual_machine" "ubuntu_server" {
+  name              = "ubuntu-server"
+  cpu               = 2
+  memory            = 2048
+  firmware          = "uefi"
+  os                = "linux"
+  inject_agent      = true
+  cloudinit_enabled = true
+  disk {
+    disk_driver = "virtio"
+    storage_id  = hiveio_storage_pool.vms.id
+    filename    = "ubuntu-server-24.04.qcow2"
+    type        = "disk"
+  }
+  interface {
+    emulation = "virtio"
+    network   = "prod"
+    vlan      = 0
+  }
+  cloudinit {
+    hostname = "ubuntu-server"
+    user {
+      name                = "ubuntu"
+      groups              = ["sudo"]
+      sudo                = "ALL=(ALL) NOPASSWD:ALL"
+      shell               = "/bin/bash"
+      ssh_authorized_keys = [file("~/.ssh/id_ed25519.pub")]
+    }
+    packages = ["qemu-guest-agent"]
+    runcmd   = ["systemctl enable --now qemu-guest-agent"]
+    network {
+      name        = "eth0"
+      addresses   = ["192.168.10.20/24"]
+      gateway     = "192.168.10.1"
+      nameservers = ["192.168.10.1"]
+    }
+  }
+}
@@ -0,0 +1,8 @@
+resource "hiveio_backup" "kubuntu" {
+  guest_name = hiveio_virtual_machine.kubuntu.guest_name
+  storage_id = hiveio_storage_pool.backups.id
+
+  triggers = {
+    release = "2026.10"
+  }
+}
@@ -0,0 +1,22 @@
+data "hiveio_backups" "kubuntu" {
+  pool_id = hiveio_virtual_machine.kubuntu.id
+}
+
+resource "hiveio_backup_restore" "kubuntu" {
+  guest_name = data.hiveio_backups.kubuntu.backups[0].guest_name
+  backup     = data.hiveio_backups.kubuntu.backups[0].backup
+}
+
+# restore the backup as a new virtual machine
+resource "hiveio_virtual_machine" "kubuntu_restored" {
+  name = "kubuntu-restored"
+  clone_from {
+    vm_id = hiveio_virtual_machine.kubuntu.id
+  }
+}
+
+resource "hiveio_backup_restore" "kubuntu_restored" {
+  guest_name = data.hiveio_backups.kubuntu.backups[0].guest_name
+  backup     = data.hiveio_backups.kubuntu.backups[0].backup
+  vm_id      = hiveio_virtual_machine.kubuntu_restored.id
+}
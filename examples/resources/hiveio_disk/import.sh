@@ -0,0 +1,2 @@
+# Disks are imported as <storage pool name or id>/<filename>
+terraform import hiveio_disk.kubuntu nfs/kubuntu.qcow2
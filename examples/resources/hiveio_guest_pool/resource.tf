@@ -11,6 +11,14 @@ resource "hiveio_guest_pool" "win10_pool" {
   persistent   = false
   storage_type = "nfs"
   storage_id   = hiveio_storage_pool.vms.id
+  # keep more desktops running during business hours
+  schedule {
+    days     = "1-5"
+    start    = "07:00"
+    end      = "19:00"
+    timezone = "America/Chicago"
+    density  = [20, 40]
+  }
 }
 
 #Create a non-persistent ubuntu pool on disk
@@ -25,9 +33,19 @@ resource "hiveio_guest_pool" "ubuntu_pool" {
   persistent   = false
   storage_type = "disk"
   storage_id   = "disk"
+  interface {
+    network = "prod"
+    vlan    = 120
+  }
   backup {
     enabled   = true
     frequency = "daily"
     target    = hiveio_storage_pool.backup.id
   }
+  rollout {
+    batch_size      = 2
+    max_unavailable = 2
+    session_policy  = "wait"
+    session_timeout = "1h"
+  }
 }
\ No newline at end of file
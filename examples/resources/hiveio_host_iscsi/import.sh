@@ -0,0 +1,2 @@
+# iSCSI sessions are imported as <hostid>/<portal>/<target>
+terraform import hiveio_host_iscsi.disk 4c4c4544-0042-3510-8052-b3c04f4e4c32/192.168.1.20:3260/iqn.2005-10.org.freenas.ctl:hive
@@ -0,0 +1,2 @@
+# Host networks are imported as <hostid>/<network name>
+terraform import hiveio_host_network.storage 4c4c4544-0042-3510-8052-b3c04f4e4c32/storage
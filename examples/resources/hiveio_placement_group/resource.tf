@@ -0,0 +1,9 @@
+resource "hiveio_placement_group" "db" {
+  name   = "db"
+  policy = "spread"
+  members = [
+    hiveio_virtual_machine.db1.id,
+    hiveio_virtual_machine.db2.id,
+    hiveio_virtual_machine.db3.id,
+  ]
+}
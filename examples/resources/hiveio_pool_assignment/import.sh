@@ -0,0 +1,2 @@
+# Pool assignments are imported as <pool id> and guest assignments as <pool id>/<guest name>
+terraform import hiveio_pool_assignment.jdoe 0b7c3ea5-5d22-4a8c-9d5b-6c5b2f1a3e11/WIN10-0001
@@ -0,0 +1,14 @@
+# Entitle an AD group to a virtual machine
+resource "hiveio_pool_assignment" "support" {
+  pool_id  = hiveio_virtual_machine.support_vm.id
+  realm    = "EXAMPLE"
+  ad_group = "support-desktops"
+}
+
+# Give a user a specific desktop in a persistent pool
+resource "hiveio_pool_assignment" "jdoe" {
+  pool_id    = hiveio_guest_pool.win10_pool.id
+  realm      = "EXAMPLE"
+  username   = "jdoe"
+  guest_name = "WIN10001"
+}
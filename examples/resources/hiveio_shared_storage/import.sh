@@ -0,0 +1,3 @@
+# Shared storage can be imported by id or by name
+terraform import hiveio_shared_storage.shared 3f6c1c4e-4a9b-4f3e-9a51-0c7ad3d6b7a2
+terraform import hiveio_shared_storage.shared name:shared
@@ -0,0 +1,3 @@
+# Storage pools can be imported by id or by name
+terraform import hiveio_storage_pool.nfs 3f6c1c4e-4a9b-4f3e-9a51-0c7ad3d6b7a2
+terraform import hiveio_storage_pool.nfs name:nfs
@@ -0,0 +1,2 @@
+# Templates are imported by name
+terraform import hiveio_template.kubuntu kubuntu
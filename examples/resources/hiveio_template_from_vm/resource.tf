@@ -0,0 +1,5 @@
+resource "hiveio_template_from_vm" "ubuntu_golden" {
+  name       = "ubuntu-golden"
+  vm_id      = hiveio_virtual_machine.ubuntu_server.id
+  storage_id = hiveio_storage_pool.templates.id
+}
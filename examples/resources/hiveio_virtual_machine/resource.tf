@@ -22,4 +22,11 @@ resource "hiveio_virtual_machine" "win10" {
     frequency = "daily"
     target    = hiveio_storage_pool.backup.id
   }
-}
\ No newline at end of file
+}
+resource "hiveio_virtual_machine" "win10_clone" {
+  name = "win10-clone"
+  clone_from {
+    template = "win10"
+    mode     = "linked"
+  }
+}
@@ -0,0 +1,3 @@
+resource "hiveio_vm_snapshot" "before_upgrade" {
+  vm_id = hiveio_virtual_machine.kubuntu.id
+}
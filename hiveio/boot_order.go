@@ -0,0 +1,143 @@
+package hiveio
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+const cdromDiskType = "CD-ROM"
+
+var bootOrderSchema = schema.Schema{
+	Type:        schema.TypeList,
+	Description: "Devices to boot from in order of priority. Entries reference a block by index such as `cdrom.0` or `disk.1`. Defaults to the current boot order when unset.",
+	Optional:    true,
+	Computed:    true,
+	Elem: &schema.Schema{
+		Type:         schema.TypeString,
+		ValidateFunc: validateBootDevice,
+	},
+}
+
+func isCdrom(diskType string) bool {
+	return strings.EqualFold(strings.ReplaceAll(diskType, "-", ""), "cdrom")
+}
+
+func validateDiskType(val interface{}, key string) (warns []string, errs []error) {
+	if isCdrom(val.(string)) {
+		errs = append(errs, fmt.Errorf("%q cannot be a cdrom, use a cdrom block for installation media", key))
+	}
+	return
+}
+
+func parseBootDevice(device string) (string, int, error) {
+	parts := strings.SplitN(device, ".", 2)
+	if len(parts) != 2 || (parts[0] != "disk" && parts[0] != "cdrom") {
+		return "", 0, fmt.Errorf("boot device %q must be in the form disk.<index> or cdrom.<index>", device)
+	}
+	index, err := strconv.Atoi(parts[1])
+	if err != nil || index < 0 {
+		return "", 0, fmt.Errorf("boot device %q has an invalid index", device)
+	}
+	return parts[0], index, nil
+}
+
+func validateBootDevice(val interface{}, key string) (warns []string, errs []error) {
+	if _, _, err := parseBootDevice(val.(string)); err != nil {
+		errs = append(errs, fmt.Errorf("%q: %w", key, err))
+	}
+	return
+}
+
+// validateBootOrder checks that every boot_order entry references a configured disk or cdrom block
+func validateBootOrder(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	seen := make(map[string]bool)
+	for _, v := range d.Get("boot_order").([]interface{}) {
+		device, ok := v.(string)
+		if !ok {
+			continue
+		}
+		if seen[device] {
+			return fmt.Errorf("boot_order: %s is listed more than once", device)
+		}
+		seen[device] = true
+		kind, index, err := parseBootDevice(device)
+		if err != nil {
+			return err
+		}
+		if index >= d.Get(kind+".#").(int) {
+			return fmt.Errorf("boot_order: %s does not match a configured %s block", device, kind)
+		}
+	}
+	return nil
+}
+
+// bootPositions maps each device in boot_order to its 1 based boot priority
+func bootPositions(d *schema.ResourceData) map[string]int {
+	positions := make(map[string]int)
+	for i, v := range d.Get("boot_order").([]interface{}) {
+		positions[v.(string)] = i + 1
+	}
+	return positions
+}
+
+// poolBootOrder rebuilds boot_order from the bootOrder values of a pool's disks.
+// keys holds the disk.N or cdrom.N reference for each entry of disks.
+func poolBootOrder(disks []*rest.PoolDisk, keys []string) []string {
+	type bootDevice struct {
+		order int
+		key   string
+	}
+	var devices []bootDevice
+	for i, disk := range disks {
+		if disk.BootOrder > 0 && keys[i] != "" {
+			devices = append(devices, bootDevice{disk.BootOrder, keys[i]})
+		}
+	}
+	sort.SliceStable(devices, func(i, j int) bool {
+		return devices[i].order < devices[j].order
+	})
+	bootOrder := make([]string, len(devices))
+	for i, device := range devices {
+		bootOrder[i] = device.key
+	}
+	return bootOrder
+}
+
+// templateDeviceOrder returns the disk and cdrom references of a template in the
+// order they are sent to the api. Templates have no per disk boot order so the
+// devices in boot_order are listed first followed by the rest in their original order.
+func templateDeviceOrder(bootOrder []string, nDisks, nCdroms int) []string {
+	listed := make(map[string]bool)
+	keys := make([]string, 0, nDisks+nCdroms)
+	for _, key := range bootOrder {
+		listed[key] = true
+		keys = append(keys, key)
+	}
+	for i := 0; i < nDisks; i++ {
+		if key := fmt.Sprintf("disk.%d", i); !listed[key] {
+			keys = append(keys, key)
+		}
+	}
+	for i := 0; i < nCdroms; i++ {
+		if key := fmt.Sprintf("cdrom.%d", i); !listed[key] {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// validateSecureBoot rejects secure boot on guests that do not use uefi firmware
+func validateSecureBoot(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	// firmware is unknown or empty until it is inherited from clone_from, which checks it then
+	firmware := d.Get("firmware").(string)
+	if d.Get("secure_boot").(bool) && d.NewValueKnown("firmware") && firmware != "" && firmware != "uefi" {
+		return fmt.Errorf("secure_boot requires firmware to be uefi")
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package hiveio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func cloneFromSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:             schema.TypeList,
+		Description:      "Create the vm from a template or another vm. The disks are copied or backed by the source disks and cpu, memory, os, firmware, display driver, disks, interfaces and broker settings are inherited unless they are set on the vm. clone_from cannot be read back, so it is ignored for imported vms.",
+		Optional:         true,
+		ForceNew:         true,
+		MaxItems:         1,
+		DiffSuppressFunc: suppressImportedCloneFrom,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"template": {
+					Type:             schema.TypeString,
+					Description:      "The name of the template to clone.",
+					Optional:         true,
+					ForceNew:         true,
+					ExactlyOneOf:     []string{"clone_from.0.template", "clone_from.0.vm_id"},
+					DiffSuppressFunc: suppressImportedCloneFrom,
+				},
+				"vm_id": {
+					Type:             schema.TypeString,
+					Description:      "The id of the hiveio_virtual_machine to clone. The disks are copied as they are, so the source should be shut down for a consistent copy.",
+					Optional:         true,
+					ForceNew:         true,
+					DiffSuppressFunc: suppressImportedCloneFrom,
+				},
+				"mode": {
+					Type:             schema.TypeString,
+					Description:      "full copies the source disks and linked creates new disks backed by the source disks.",
+					Default:          "full",
+					Optional:         true,
+					ForceNew:         true,
+					ValidateFunc:     validation.StringInSlice([]string{"full", "linked"}, false),
+					DiffSuppressFunc: suppressImportedCloneFrom,
+				},
+				"storage_id": {
+					Type:             schema.TypeString,
+					Description:      "The storage pool for the cloned disks. Defaults to the storage pool of each source disk.",
+					Optional:         true,
+					ForceNew:         true,
+					DiffSuppressFunc: suppressImportedCloneFrom,
+				},
+			},
+		},
+	}
+}
+
+// suppressImportedCloneFrom ignores clone_from on vms that exist without it in state, which are
+// imported vms since clone_from is only used when the vm is created
+func suppressImportedCloneFrom(k, oldValue, newValue string, d *schema.ResourceData) bool {
+	count, _ := d.GetChange("clone_from.#")
+	return d.Id() != "" && count.(int) == 0
+}
+
+// cloneFromConfigured reports whether clone_from is in the configuration. It is used instead of the
+// planned value because clone_from is not kept in the state of imported vms.
+func cloneFromConfigured(d *schema.ResourceDiff) bool {
+	config := d.GetRawConfig()
+	if config.IsNull() || !config.IsKnown() {
+		return d.Get("clone_from.#").(int) > 0
+	}
+	v := config.GetAttr("clone_from")
+	return !v.IsKnown() || (!v.IsNull() && v.LengthInt() > 0)
+}
+
+// validateCloneSource requires the settings that are otherwise inherited when a vm is not a clone
+func validateCloneSource(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if cloneFromConfigured(d) {
+		return nil
+	}
+	config := d.GetRawConfig()
+	if config.IsNull() || !config.IsKnown() {
+		return nil
+	}
+	for _, attr := range []string{"cpu", "memory", "os"} {
+		if config.GetAttr(attr).IsNull() {
+			return fmt.Errorf("%s is required unless clone_from is set", attr)
+		}
+	}
+	return nil
+}
+
+// diffVMDefaults gives the attributes a clone inherits their defaults when the vm is not a clone.
+// They are computed so a clone keeps the inherited values, without clone_from removing them from
+// the configuration plans the change back to the default.
+func diffVMDefaults(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if cloneFromConfigured(d) {
+		return nil
+	}
+	config := d.GetRawConfig()
+	if config.IsNull() || !config.IsKnown() {
+		return nil
+	}
+	for _, attr := range []struct {
+		name  string
+		value string
+	}{{"firmware", "uefi"}, {"display_driver", "cirrus"}, {"broker_default_connection", ""}} {
+		if config.GetAttr(attr.name).IsNull() && (!d.NewValueKnown(attr.name) || d.Get(attr.name).(string) != attr.value) {
+			if err := d.SetNew(attr.name, attr.value); err != nil {
+				return err
+			}
+		}
+	}
+	for _, name := range []string{"disk", "interface", "broker_connection"} {
+		v := config.GetAttr(name)
+		if v.IsKnown() && (v.IsNull() || v.LengthInt() == 0) && (!d.NewValueKnown(name) || d.Get(name+".#").(int) > 0) {
+			if err := d.SetNew(name, []interface{}{}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cloneSource is the hardware of a template or vm to clone
+type cloneSource struct {
+	name          string
+	cpu           int
+	memory        int
+	os            string
+	firmware      string
+	displayDriver string
+	disks         []*rest.PoolDisk
+	interfaces    []interface{}
+	brokerOptions *rest.GuestBrokerOptions
+}
+
+func getCloneSource(client *rest.Client, d *schema.ResourceData) (*cloneSource, error) {
+	if name := d.Get("clone_from.0.template").(string); name != "" {
+		template, err := client.GetTemplate(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get template %s: %w", name, err)
+		}
+		src := cloneSource{
+			name:          template.Name,
+			cpu:           template.Vcpu,
+			memory:        template.Mem,
+			os:            template.OS,
+			firmware:      template.Firmware,
+			displayDriver: template.DisplayDriver,
+			brokerOptions: template.BrokerOptions,
+		}
+		for _, disk := range template.Disks {
+			if isCdrom(disk.Type) {
+				continue
+			}
+			src.disks = append(src.disks, &rest.PoolDisk{
+				DiskDriver: disk.DiskDriver,
+				Filename:   disk.Filename,
+				StorageID:  disk.StorageID,
+				Type:       disk.Type,
+			})
+		}
+		for _, iface := range template.Interfaces {
+			src.interfaces = append(src.interfaces, map[string]interface{}{
+				"network":   iface.Network,
+				"vlan":      iface.Vlan,
+				"emulation": iface.Emulation,
+			})
+		}
+		return &src, nil
+	}
+
+	id := d.Get("clone_from.0.vm_id").(string)
+	pool, err := client.GetPool(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vm %s: %w", id, err)
+	}
+	if pool.Type != "standalone" {
+		return nil, fmt.Errorf("%s is not a virtual machine", pool.Name)
+	}
+	src := cloneSource{
+		name:          pool.Name,
+		os:            pool.GuestProfile.OS,
+		firmware:      pool.GuestProfile.Firmware,
+		displayDriver: pool.GuestProfile.Vga,
+		brokerOptions: pool.GuestProfile.BrokerOptions,
+	}
+	if len(pool.GuestProfile.CPU) > 0 {
+		src.cpu = pool.GuestProfile.CPU[0]
+	}
+	if len(pool.GuestProfile.Mem) > 0 {
+		src.memory = pool.GuestProfile.Mem[0]
+	}
+	for _, disk := range pool.GuestProfile.Disks {
+		if !isCdrom(disk.Type) {
+			src.disks = append(src.disks, disk)
+		}
+	}
+	for _, iface := range pool.GuestProfile.Interfaces {
+		src.interfaces = append(src.interfaces, map[string]interface{}{
+			"network":   iface.Network,
+			"vlan":      vlanID(iface.Vlan),
+			"emulation": iface.Emulation,
+		})
+	}
+	return &src, nil
+}
+
+// cloneDisk copies or links a source disk into filename and waits for the task
+func cloneDisk(ctx context.Context, client *rest.Client, disk *rest.PoolDisk, storageID, filename, mode string) error {
+	srcStorage, err := client.GetStoragePool(disk.StorageID)
+	if err != nil {
+		return err
+	}
+	storage := srcStorage
+	if storageID != disk.StorageID {
+		storage, err = client.GetStoragePool(storageID)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := storage.DiskInfo(client, filename); err == nil {
+		return fmt.Errorf("%s already exists in storage pool %s", filename, storage.Name)
+	}
+
+	var task *rest.Task
+	if mode == "linked" {
+		info, err := srcStorage.DiskInfo(client, disk.Filename)
+		if err != nil {
+			return err
+		}
+		backingFile := &rest.StorageDisk{
+			StorageID: disk.StorageID,
+			Filename:  disk.Filename,
+			Format:    info.Format,
+		}
+		task, err = storage.CreateDisk(client, filename, "qcow2", (info.VirtualSize+1<<30-1)>>30, backingFile)
+		if err != nil {
+			return err
+		}
+	} else {
+		task, err = srcStorage.ConvertDisk(client, disk.Filename, storageID, filename, "qcow2")
+		if err != nil {
+			return err
+		}
+	}
+	if task == nil {
+		return fmt.Errorf("failed to clone %s: Task was not returned", disk.Filename)
+	}
+	task, err = task.WaitForTaskWithContext(ctx, client, false)
+	if err != nil {
+		return err
+	}
+	if task.State == "failed" {
+		return fmt.Errorf("failed to clone %s: %s", disk.Filename, task.Message)
+	}
+	return nil
+}
+
+// deleteClonedDisks removes the disks cloned for a vm that could not be created
+func deleteClonedDisks(client *rest.Client, disks []*rest.PoolDisk) error {
+	var errs []error
+	for _, disk := range disks {
+		storage, err := client.GetStoragePool(disk.StorageID)
+		if err == nil {
+			err = storage.DeleteFile(client, disk.Filename)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete cloned disk %s: %w", disk.Filename, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// applyCloneSource clones the disks of clone_from and sets every inherited attribute that is
+// not set on the vm. The cloned disks are returned so they can be deleted if the vm is not created.
+func applyCloneSource(ctx context.Context, client *rest.Client, d *schema.ResourceData) (cloned []*rest.PoolDisk, err error) {
+	if d.Get("clone_from.#").(int) == 0 {
+		return nil, nil
+	}
+	src, err := getCloneSource(client, d)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := d.GetOk("cpu"); !ok {
+		d.Set("cpu", src.cpu)
+	}
+	if _, ok := d.GetOk("memory"); !ok {
+		d.Set("memory", src.memory)
+	}
+	if _, ok := d.GetOk("os"); !ok {
+		d.Set("os", src.os)
+	}
+	if _, ok := d.GetOk("firmware"); !ok {
+		d.Set("firmware", src.firmware)
+	}
+	if firmware := d.Get("firmware").(string); d.Get("secure_boot").(bool) && firmware != "" && firmware != "uefi" {
+		return nil, fmt.Errorf("secure_boot requires firmware to be uefi, %s uses %s", src.name, firmware)
+	}
+	if _, ok := d.GetOk("display_driver"); !ok {
+		d.Set("display_driver", src.displayDriver)
+	}
+	if d.Get("interface.#").(int) == 0 {
+		d.Set("interface", src.interfaces)
+	}
+	if d.Get("broker_connection.#").(int) == 0 && src.brokerOptions != nil {
+		d.Set("broker_default_connection", src.brokerOptions.DefaultConnection)
+		d.Set("broker_connection", brokerConnections(src.brokerOptions))
+	}
+
+	if d.Get("disk.#").(int) > 0 {
+		return nil, nil
+	}
+	mode := d.Get("clone_from.0.mode").(string)
+	name := d.Get("name").(string)
+	disks := make([]interface{}, len(src.disks))
+	for i, disk := range src.disks {
+		storageID := d.Get("clone_from.0.storage_id").(string)
+		if storageID == "" {
+			storageID = disk.StorageID
+		}
+		filename := fmt.Sprintf("%s-disk%d.qcow2", name, i)
+		if err := cloneDisk(ctx, client, disk, storageID, filename, mode); err != nil {
+			err = fmt.Errorf("failed to clone disks from %s: %w", src.name, err)
+			return nil, errors.Join(err, deleteClonedDisks(client, cloned))
+		}
+		cloned = append(cloned, &rest.PoolDisk{StorageID: storageID, Filename: filename})
+		disks[i] = map[string]interface{}{
+			"type":        disk.Type,
+			"storage_id":  storageID,
+			"filename":    filename,
+			"disk_driver": disk.DiskDriver,
+		}
+	}
+	if err := d.Set("disk", disks); err != nil {
+		return nil, errors.Join(err, deleteClonedDisks(client, cloned))
+	}
+	return cloned, nil
+}
@@ -0,0 +1,529 @@
+package hiveio
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	macAddressRegex = regexp.MustCompile(`^([0-9A-Fa-f]{2}:){5}[0-9A-Fa-f]{2}$`)
+	fileModeRegex   = regexp.MustCompile(`^0?[0-7]{3,4}$`)
+)
+
+func cloudInitSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:          schema.TypeList,
+		Description:   "Structured cloud-init settings rendered by the provider into cloud-config userdata and a version 2 network config. Requires `cloudinit_enabled`.",
+		Optional:      true,
+		MaxItems:      1,
+		ConflictsWith: []string{"cloudinit_userdata"},
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"hostname": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"ssh_authorized_keys": {
+					Type:        schema.TypeList,
+					Description: "Public keys to add to the default user.",
+					Optional:    true,
+					Elem: &schema.Schema{
+						Type: schema.TypeString,
+					},
+				},
+				"user": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"name": {
+								Type:     schema.TypeString,
+								Required: true,
+							},
+							"groups": {
+								Type:     schema.TypeList,
+								Optional: true,
+								Elem: &schema.Schema{
+									Type: schema.TypeString,
+								},
+							},
+							"sudo": {
+								Type:        schema.TypeString,
+								Description: "A sudoers rule for the user such as `ALL=(ALL) NOPASSWD:ALL`.",
+								Optional:    true,
+							},
+							"shell": {
+								Type:     schema.TypeString,
+								Optional: true,
+							},
+							"lock_passwd": {
+								Type:     schema.TypeBool,
+								Default:  true,
+								Optional: true,
+							},
+							"hashed_passwd": {
+								Type:      schema.TypeString,
+								Optional:  true,
+								Sensitive: true,
+							},
+							"ssh_authorized_keys": {
+								Type:     schema.TypeList,
+								Optional: true,
+								Elem: &schema.Schema{
+									Type: schema.TypeString,
+								},
+							},
+						},
+					},
+				},
+				"packages": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem: &schema.Schema{
+						Type: schema.TypeString,
+					},
+				},
+				"write_files": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"path": {
+								Type:     schema.TypeString,
+								Required: true,
+								ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+									if !strings.HasPrefix(val.(string), "/") {
+										errs = append(errs, fmt.Errorf("%q must be an absolute path", key))
+									}
+									return
+								},
+							},
+							"content": {
+								Type:     schema.TypeString,
+								Required: true,
+							},
+							"owner": {
+								Type:     schema.TypeString,
+								Optional: true,
+							},
+							"permissions": {
+								Type:     schema.TypeString,
+								Optional: true,
+								ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+									if !fileModeRegex.MatchString(val.(string)) {
+										errs = append(errs, fmt.Errorf("%q must be an octal file mode such as 0644", key))
+									}
+									return
+								},
+							},
+						},
+					},
+				},
+				"runcmd": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem: &schema.Schema{
+						Type: schema.TypeString,
+					},
+				},
+				"network": {
+					Type:          schema.TypeList,
+					Optional:      true,
+					ConflictsWith: []string{"cloudinit_networkconfig"},
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"name": {
+								Type:        schema.TypeString,
+								Description: "The interface name in the guest such as `eth0`.",
+								Required:    true,
+							},
+							"mac_address": {
+								Type:        schema.TypeString,
+								Description: "Match the interface by mac address and rename it to `name`.",
+								Optional:    true,
+								ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+									if !macAddressRegex.MatchString(val.(string)) {
+										errs = append(errs, fmt.Errorf("%q must be a mac address", key))
+									}
+									return
+								},
+							},
+							"dhcp4": {
+								Type:     schema.TypeBool,
+								Default:  false,
+								Optional: true,
+							},
+							"dhcp6": {
+								Type:     schema.TypeBool,
+								Default:  false,
+								Optional: true,
+							},
+							"addresses": {
+								Type:        schema.TypeList,
+								Description: "Static addresses in CIDR notation.",
+								Optional:    true,
+								Elem: &schema.Schema{
+									Type: schema.TypeString,
+									ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+										if _, _, err := net.ParseCIDR(val.(string)); err != nil {
+											errs = append(errs, fmt.Errorf("%q must be an address in CIDR notation: %w", key, err))
+										}
+										return
+									},
+								},
+							},
+							"gateway": {
+								Type:         schema.TypeString,
+								Optional:     true,
+								ValidateFunc: validateIPAddress,
+							},
+							"nameservers": {
+								Type:     schema.TypeList,
+								Optional: true,
+								Elem: &schema.Schema{
+									Type:         schema.TypeString,
+									ValidateFunc: validateIPAddress,
+								},
+							},
+							"search_domains": {
+								Type:     schema.TypeList,
+								Optional: true,
+								Elem: &schema.Schema{
+									Type: schema.TypeString,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func validateIPAddress(val interface{}, key string) (warns []string, errs []error) {
+	if net.ParseIP(val.(string)) == nil {
+		errs = append(errs, fmt.Errorf("%q must be an ip address", key))
+	}
+	return
+}
+
+type cloudConfigUser struct {
+	Name              string   `yaml:"name"`
+	Groups            string   `yaml:"groups,omitempty"`
+	Sudo              string   `yaml:"sudo,omitempty"`
+	Shell             string   `yaml:"shell,omitempty"`
+	LockPasswd        bool     `yaml:"lock_passwd"`
+	HashedPasswd      string   `yaml:"hashed_passwd,omitempty"`
+	SSHAuthorizedKeys []string `yaml:"ssh_authorized_keys,omitempty"`
+}
+
+type cloudConfigFile struct {
+	Path        string `yaml:"path"`
+	Content     string `yaml:"content"`
+	Owner       string `yaml:"owner,omitempty"`
+	Permissions string `yaml:"permissions,omitempty"`
+}
+
+type cloudConfig struct {
+	Hostname          string            `yaml:"hostname,omitempty"`
+	Users             []interface{}     `yaml:"users,omitempty"`
+	SSHAuthorizedKeys []string          `yaml:"ssh_authorized_keys,omitempty"`
+	Packages          []string          `yaml:"packages,omitempty"`
+	WriteFiles        []cloudConfigFile `yaml:"write_files,omitempty"`
+	Runcmd            []string          `yaml:"runcmd,omitempty"`
+}
+
+type networkConfigNameservers struct {
+	Addresses []string `yaml:"addresses,omitempty"`
+	Search    []string `yaml:"search,omitempty"`
+}
+
+type networkConfigEthernet struct {
+	Match       map[string]string         `yaml:"match,omitempty"`
+	SetName     string                    `yaml:"set-name,omitempty"`
+	DHCP4       bool                      `yaml:"dhcp4"`
+	DHCP6       bool                      `yaml:"dhcp6,omitempty"`
+	Addresses   []string                  `yaml:"addresses,omitempty"`
+	Gateway4    string                    `yaml:"gateway4,omitempty"`
+	Gateway6    string                    `yaml:"gateway6,omitempty"`
+	Nameservers *networkConfigNameservers `yaml:"nameservers,omitempty"`
+}
+
+type networkConfig struct {
+	Version   int                              `yaml:"version"`
+	Ethernets map[string]networkConfigEthernet `yaml:"ethernets"`
+}
+
+func stringList(v interface{}) []string {
+	list, _ := v.([]interface{})
+	var result []string
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// renderCloudInit renders the cloudinit block into cloud-config userdata and a
+// version 2 network config. networkConfig is empty when no network is configured.
+func renderCloudInit(block []interface{}) (userData string, networkData string, err error) {
+	if len(block) == 0 || block[0] == nil {
+		return "", "", nil
+	}
+	settings := block[0].(map[string]interface{})
+
+	config := cloudConfig{
+		Hostname:          settings["hostname"].(string),
+		SSHAuthorizedKeys: stringList(settings["ssh_authorized_keys"]),
+		Packages:          stringList(settings["packages"]),
+		Runcmd:            stringList(settings["runcmd"]),
+	}
+	users, _ := settings["user"].([]interface{})
+	if len(users) > 0 {
+		// keep the image's default user so ssh_authorized_keys still applies to it
+		config.Users = append(config.Users, "default")
+	}
+	seenUsers := make(map[string]bool)
+	for _, v := range users {
+		user := v.(map[string]interface{})
+		name := user["name"].(string)
+		if seenUsers[name] {
+			return "", "", fmt.Errorf("cloudinit: user %s is defined more than once", name)
+		}
+		seenUsers[name] = true
+		config.Users = append(config.Users, cloudConfigUser{
+			Name:              name,
+			Groups:            strings.Join(stringList(user["groups"]), ", "),
+			Sudo:              user["sudo"].(string),
+			Shell:             user["shell"].(string),
+			LockPasswd:        user["lock_passwd"].(bool),
+			HashedPasswd:      user["hashed_passwd"].(string),
+			SSHAuthorizedKeys: stringList(user["ssh_authorized_keys"]),
+		})
+	}
+	files, _ := settings["write_files"].([]interface{})
+	for _, v := range files {
+		file := v.(map[string]interface{})
+		config.WriteFiles = append(config.WriteFiles, cloudConfigFile{
+			Path:        file["path"].(string),
+			Content:     file["content"].(string),
+			Owner:       file["owner"].(string),
+			Permissions: file["permissions"].(string),
+		})
+	}
+	data, err := marshalYAML(config)
+	if err != nil {
+		return "", "", fmt.Errorf("cloudinit: failed to render userdata: %w", err)
+	}
+	userData = "#cloud-config\n" + data
+
+	interfaces, _ := settings["network"].([]interface{})
+	if len(interfaces) == 0 {
+		return userData, "", nil
+	}
+	network := networkConfig{
+		Version:   2,
+		Ethernets: make(map[string]networkConfigEthernet),
+	}
+	for _, v := range interfaces {
+		iface := v.(map[string]interface{})
+		name := iface["name"].(string)
+		if _, ok := network.Ethernets[name]; ok {
+			return "", "", fmt.Errorf("cloudinit: network interface %s is defined more than once", name)
+		}
+		ethernet := networkConfigEthernet{
+			DHCP4:     iface["dhcp4"].(bool),
+			DHCP6:     iface["dhcp6"].(bool),
+			Addresses: stringList(iface["addresses"]),
+		}
+		if !ethernet.DHCP4 && !ethernet.DHCP6 && len(ethernet.Addresses) == 0 {
+			return "", "", fmt.Errorf("cloudinit: network interface %s needs dhcp4, dhcp6 or at least one address", name)
+		}
+		if mac := iface["mac_address"].(string); mac != "" {
+			ethernet.Match = map[string]string{"macaddress": strings.ToLower(mac)}
+			ethernet.SetName = name
+		}
+		if gateway := iface["gateway"].(string); gateway != "" {
+			if len(ethernet.Addresses) == 0 {
+				return "", "", fmt.Errorf("cloudinit: network interface %s has a gateway without static addresses", name)
+			}
+			if ip := net.ParseIP(gateway); ip != nil && ip.To4() == nil {
+				ethernet.Gateway6 = gateway
+			} else {
+				ethernet.Gateway4 = gateway
+			}
+		}
+		nameservers := stringList(iface["nameservers"])
+		search := stringList(iface["search_domains"])
+		if len(nameservers) > 0 || len(search) > 0 {
+			ethernet.Nameservers = &networkConfigNameservers{
+				Addresses: nameservers,
+				Search:    search,
+			}
+		}
+		network.Ethernets[name] = ethernet
+	}
+	data, err = marshalYAML(network)
+	if err != nil {
+		return "", "", fmt.Errorf("cloudinit: failed to render network config: %w", err)
+	}
+	return userData, data, nil
+}
+
+func marshalYAML(v interface{}) (string, error) {
+	var buf strings.Builder
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return "", err
+	}
+	err := encoder.Close()
+	return buf.String(), err
+}
+
+// validateCloudInit renders the cloudinit block at plan time so configuration errors are reported before apply
+func validateCloudInit(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	block, ok := d.Get("cloudinit").([]interface{})
+	if !ok || len(block) == 0 || !d.NewValueKnown("cloudinit") {
+		return nil
+	}
+	if !d.Get("cloudinit_enabled").(bool) {
+		return fmt.Errorf("cloudinit requires cloudinit_enabled to be true")
+	}
+	_, _, err := renderCloudInit(block)
+	return err
+}
+
+var guestTemplateVariableRegex = regexp.MustCompile(`{{-?\s*(guest_name|seed_index|ip_address|pool_name)\b`)
+
+// jinjaTagRegex matches jinja expressions and statements, comments are matched so they can be skipped
+var jinjaTagRegex = regexp.MustCompile(`(?s){#.*?#}|{{(.*?)}}|{%(.*?)%}`)
+
+// templateUsesVariable reports whether a jinja expression or statement in text refers to the variable
+func templateUsesVariable(text, name string) bool {
+	variable := regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`)
+	for _, match := range jinjaTagRegex.FindAllStringSubmatch(text, -1) {
+		if variable.MatchString(match[1]) || variable.MatchString(match[2]) {
+			return true
+		}
+	}
+	return false
+}
+
+func guestVariablesSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Description: "Render `cloudinit_userdata` as a jinja template in each guest with the variables `guest_name`, `seed_index`, `pool_name` and `ip_address`. `seed_index` is the number after the seed in the guest name, cloud-init fails to render the userdata of a guest whose name is not the seed followed by a number.",
+		Optional:    true,
+		MaxItems:    1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"ip_range_start": {
+					Type:         schema.TypeString,
+					Description:  "The `ip_address` given to the guest with seed index 1. Each following guest gets the next address.",
+					Optional:     true,
+					ValidateFunc: validateIPv4Address,
+				},
+				"ip_range_end": {
+					Type:         schema.TypeString,
+					Description:  "The last address that can be given to a guest.",
+					Optional:     true,
+					ValidateFunc: validateIPv4Address,
+				},
+			},
+		},
+	}
+}
+
+func validateIPv4Address(val interface{}, key string) (warns []string, errs []error) {
+	if ip := net.ParseIP(val.(string)); ip == nil || ip.To4() == nil {
+		errs = append(errs, fmt.Errorf("%q must be an ipv4 address", key))
+	}
+	return
+}
+
+func ipv4ToInt(address string) (uint32, error) {
+	ip := net.ParseIP(address).To4()
+	if ip == nil {
+		return 0, fmt.Errorf("%s is not an ipv4 address", address)
+	}
+	return uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3]), nil
+}
+
+// renderGuestTemplate prefixes userdata with a jinja header that cloud-init renders in
+// each clone, so every guest of a pool can get its own hostname or address.
+func renderGuestTemplate(userData, poolName, seed, ipRangeStart string) (string, error) {
+	lines := strings.SplitN(userData, "\n", 2)
+	if strings.TrimSpace(lines[0]) == "## template: jinja" {
+		userData = ""
+		if len(lines) > 1 {
+			userData = lines[1]
+		}
+	}
+	var header strings.Builder
+	header.WriteString("## template: jinja\n")
+	header.WriteString("{%- set guest_name = v1.local_hostname | upper -%}\n")
+	// seed_index is the counter after the seed. A guest named otherwise fails to render with the
+	// message in the key of the undefined lookup instead of getting index 0.
+	seed = strings.ToUpper(seed)
+	fmt.Fprintf(&header, "{%%- set seed_suffix = guest_name[%d:].lstrip(\"-_\") -%%}\n", len(seed))
+	fmt.Fprintf(&header, "{%%- if not guest_name.startswith(%q) or not seed_suffix.isdigit() -%%}\n", seed)
+	fmt.Fprintf(&header, "{{- {}[\"seed_index: guest name \" ~ guest_name ~ %q]() -}}\n", " is not the seed "+seed+" followed by a number")
+	header.WriteString("{%- endif -%}\n")
+	header.WriteString("{%- set seed_index = seed_suffix | int -%}\n")
+	fmt.Fprintf(&header, "{%%- set pool_name = %q -%%}\n", poolName)
+	if ipRangeStart != "" {
+		start, err := ipv4ToInt(ipRangeStart)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&header, "{%%- set ip_number = %d + seed_index - 1 -%%}\n", start)
+		header.WriteString("{%- set ip_address = \"%d.%d.%d.%d\" | format(ip_number // 16777216 % 256, ip_number // 65536 % 256, ip_number // 256 % 256, ip_number % 256) -%}\n")
+	}
+	return header.String() + userData, nil
+}
+
+// validateGuestTemplate checks the guest variables of a pool at plan time
+func validateGuestTemplate(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if guestTemplateVariableRegex.MatchString(d.Get("cloudinit_networkconfig").(string)) {
+		return fmt.Errorf("cloudinit_networkconfig cannot use guest variables, cloud-init only renders templates in userdata")
+	}
+	settings, ok := d.Get("cloudinit_guest_variables").([]interface{})
+	if !ok || len(settings) == 0 || settings[0] == nil {
+		return nil
+	}
+	vars := settings[0].(map[string]interface{})
+	userData := d.Get("cloudinit_userdata").(string)
+	start, end := vars["ip_range_start"].(string), vars["ip_range_end"].(string)
+	if start == "" {
+		if templateUsesVariable(userData, "ip_address") {
+			return fmt.Errorf("cloudinit_guest_variables: ip_range_start is required to use ip_address")
+		}
+		return nil
+	}
+	startIP, err := ipv4ToInt(start)
+	if err != nil {
+		return fmt.Errorf("cloudinit_guest_variables: %w", err)
+	}
+	if end == "" {
+		return nil
+	}
+	endIP, err := ipv4ToInt(end)
+	if err != nil {
+		return fmt.Errorf("cloudinit_guest_variables: %w", err)
+	}
+	if endIP < startIP {
+		return fmt.Errorf("cloudinit_guest_variables: ip_range_end %s is before ip_range_start %s", end, start)
+	}
+	if density, ok := d.Get("density.1").(int); ok && uint32(density) > endIP-startIP+1 {
+		return fmt.Errorf("cloudinit_guest_variables: the range %s-%s has %d addresses but the pool can have %d guests", start, end, endIP-startIP+1, density)
+	}
+	return nil
+}
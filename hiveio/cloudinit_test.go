@@ -0,0 +1,201 @@
+package hiveio
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestRenderGuestTemplate(t *testing.T) {
+	tests := []struct {
+		name         string
+		userData     string
+		seed         string
+		ipRangeStart string
+		want         []string
+		notWant      []string
+		wantErr      bool
+	}{
+		{
+			name:     "seed index",
+			userData: "hostname: {{ guest_name }}",
+			seed:     "desk",
+			want: []string{
+				`{%- set seed_suffix = guest_name[4:].lstrip("-_") -%}`,
+				`{%- if not guest_name.startswith("DESK") or not seed_suffix.isdigit() -%}`,
+				`" is not the seed DESK followed by a number"`,
+				`{%- set seed_index = seed_suffix | int -%}`,
+				`{%- set pool_name = "desktops" -%}`,
+			},
+			notWant: []string{"ip_address"},
+		},
+		{
+			name:     "seed ending in digits",
+			userData: "hostname: {{ guest_name }}",
+			seed:     "WIN10",
+			want:     []string{`guest_name[5:]`, `startswith("WIN10")`},
+		},
+		{
+			name:         "ip range",
+			userData:     "## template: jinja\nhostname: {{ guest_name }}",
+			seed:         "DESK",
+			ipRangeStart: "10.0.0.10",
+			want:         []string{"{%- set ip_number = 167772170 + seed_index - 1 -%}", "{%- set ip_address = "},
+		},
+		{
+			name:         "invalid ip",
+			seed:         "DESK",
+			ipRangeStart: "10.0.0",
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderGuestTemplate(tt.userData, "desktops", tt.seed, tt.ipRangeStart)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(got, "## template: jinja\n") || strings.Count(got, "## template: jinja") != 1 {
+				t.Errorf("expected one jinja header line at the start:\n%s", got)
+			}
+			if !strings.HasSuffix(got, "hostname: {{ guest_name }}") {
+				t.Errorf("expected the userdata after the header:\n%s", got)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected %s in:\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("did not expect %s in:\n%s", notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderCloudInit(t *testing.T) {
+	tests := []struct {
+		name        string
+		block       map[string]interface{}
+		wantUser    []string
+		wantNetwork []string
+		wantErr     string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "users and files",
+			block: map[string]interface{}{
+				"hostname":    "kubuntu",
+				"packages":    []interface{}{"htop"},
+				"user":        []interface{}{map[string]interface{}{"name": "ops", "groups": []interface{}{"sudo", "adm"}, "sudo": "ALL=(ALL) NOPASSWD:ALL"}},
+				"write_files": []interface{}{map[string]interface{}{"path": "/etc/motd", "content": "hello", "permissions": "0644"}},
+			},
+			wantUser: []string{"#cloud-config\n", "hostname: kubuntu", "- default", "name: ops", "groups: sudo, adm", "lock_passwd: true", "path: /etc/motd", "- htop"},
+		},
+		{
+			name: "duplicate user",
+			block: map[string]interface{}{
+				"user": []interface{}{map[string]interface{}{"name": "ops"}, map[string]interface{}{"name": "ops"}},
+			},
+			wantErr: "user ops is defined more than once",
+		},
+		{
+			name: "static network",
+			block: map[string]interface{}{
+				"network": []interface{}{map[string]interface{}{
+					"name":        "eth0",
+					"mac_address": "52:54:00:AB:CD:EF",
+					"addresses":   []interface{}{"10.0.0.5/24", "fd00::5/64"},
+					"gateway":     "10.0.0.1",
+					"nameservers": []interface{}{"10.0.0.2"},
+				}},
+			},
+			wantUser:    []string{"#cloud-config\n"},
+			wantNetwork: []string{"version: 2", "macaddress: 52:54:00:ab:cd:ef", "set-name: eth0", "gateway4: 10.0.0.1", "- 10.0.0.2"},
+		},
+		{
+			name: "ipv6 gateway",
+			block: map[string]interface{}{
+				"network": []interface{}{map[string]interface{}{"name": "eth0", "addresses": []interface{}{"fd00::5/64"}, "gateway": "fd00::1"}},
+			},
+			wantNetwork: []string{"gateway6: fd00::1"},
+		},
+		{
+			name: "dhcp",
+			block: map[string]interface{}{
+				"network": []interface{}{map[string]interface{}{"name": "eth0", "dhcp4": true}},
+			},
+			wantNetwork: []string{"dhcp4: true"},
+		},
+		{
+			name: "no addressing",
+			block: map[string]interface{}{
+				"network": []interface{}{map[string]interface{}{"name": "eth0"}},
+			},
+			wantErr: "needs dhcp4, dhcp6 or at least one address",
+		},
+		{
+			name: "gateway without address",
+			block: map[string]interface{}{
+				"network": []interface{}{map[string]interface{}{"name": "eth0", "dhcp4": true, "gateway": "10.0.0.1"}},
+			},
+			wantErr: "has a gateway without static addresses",
+		},
+		{
+			name: "duplicate interface",
+			block: map[string]interface{}{
+				"network": []interface{}{
+					map[string]interface{}{"name": "eth0", "dhcp4": true},
+					map[string]interface{}{"name": "eth0", "dhcp6": true},
+				},
+			},
+			wantErr: "network interface eth0 is defined more than once",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := map[string]interface{}{}
+			if tt.block != nil {
+				raw["cloudinit"] = []interface{}{tt.block}
+			}
+			d := schema.TestResourceDataRaw(t, map[string]*schema.Schema{"cloudinit": cloudInitSchema()}, raw)
+			userData, networkData, err := renderCloudInit(d.Get("cloudinit").([]interface{}))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.block == nil && (userData != "" || networkData != "") {
+				t.Fatalf("expected nothing for an empty block, got %q and %q", userData, networkData)
+			}
+			for _, want := range tt.wantUser {
+				if !strings.Contains(userData, want) {
+					t.Errorf("expected %q in the userdata:\n%s", want, userData)
+				}
+			}
+			if len(tt.wantNetwork) == 0 && networkData != "" {
+				t.Errorf("expected no network config, got:\n%s", networkData)
+			}
+			for _, want := range tt.wantNetwork {
+				if !strings.Contains(networkData, want) {
+					t.Errorf("expected %q in the network config:\n%s", want, networkData)
+				}
+			}
+		})
+	}
+}
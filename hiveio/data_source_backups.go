@@ -0,0 +1,122 @@
+package hiveio
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func dataSourceBackups() *schema.Resource {
+	return &schema.Resource{
+		Description: "The backups data source lists the restore points of the guests in a pool or of a single guest",
+		ReadContext: dataSourceBackupsRead,
+		Schema: map[string]*schema.Schema{
+			"pool_id": {
+				Type:         schema.TypeString,
+				Description:  "The id of a virtual machine or guest pool to list backups for.",
+				Optional:     true,
+				ExactlyOneOf: []string{"pool_id", "guest_name"},
+			},
+			"guest_name": {
+				Type:        schema.TypeString,
+				Description: "The name of a guest to list backups for.",
+				Optional:    true,
+			},
+			"storage_id": {
+				Type:        schema.TypeString,
+				Description: "The storage pool the backups are stored in. Defaults to the backup target of the pool.",
+				Optional:    true,
+				Computed:    true,
+			},
+			"backups": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"guest_name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"backup": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+			"provider_override": &providerOverride,
+		},
+	}
+}
+
+// backupStorageID returns the configured backup target of a pool
+func backupStorageID(pool *rest.Pool) (string, error) {
+	if pool.Backup == nil || pool.Backup.TargetStorageID == "" {
+		return "", fmt.Errorf("pool %s does not have a backup target, storage_id must be provided", pool.Name)
+	}
+	return pool.Backup.TargetStorageID, nil
+}
+
+func dataSourceBackupsRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var guests []rest.Guest
+	var poolID string
+	if id, ok := d.GetOk("pool_id"); ok {
+		poolID = id.(string)
+		list, err := client.ListGuests("poolId=" + url.QueryEscape(poolID))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		for _, guest := range list {
+			if guest.PoolID == poolID {
+				guests = append(guests, guest)
+			}
+		}
+		d.SetId(poolID)
+	} else {
+		guest, err := client.GetGuest(d.Get("guest_name").(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		poolID = guest.PoolID
+		guests = append(guests, *guest)
+		d.SetId(guest.Name)
+	}
+
+	storageID := d.Get("storage_id").(string)
+	if storageID == "" {
+		pool, err := client.GetPool(poolID)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		storageID, err = backupStorageID(pool)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	backups := []interface{}{}
+	for _, guest := range guests {
+		names, err := guest.ListBackups(client, storageID)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		for _, name := range names {
+			backups = append(backups, map[string]interface{}{
+				"guest_name": guest.Name,
+				"backup":     name,
+			})
+		}
+	}
+	d.Set("storage_id", storageID)
+	d.Set("backups", backups)
+	return diag.Diagnostics{}
+}
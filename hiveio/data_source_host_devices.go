@@ -0,0 +1,182 @@
+package hiveio
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func dataSourceHostDevices() *schema.Resource {
+	return &schema.Resource{
+		Description: "Lists the pci devices, vGPU profiles and usb devices each host can pass through to guests.",
+		ReadContext: dataSourceHostDevicesRead,
+		Schema: map[string]*schema.Schema{
+			"hostid": {
+				Type:        schema.TypeString,
+				Description: "Only list the devices of this host.",
+				Optional:    true,
+			},
+			"host": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"hostid": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"hostname": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"pci_device": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"address": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"vendor_id": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"device_id": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"device_class": {
+										Type:     schema.TypeInt,
+										Computed: true,
+									},
+									"iommu_group": {
+										Type:     schema.TypeInt,
+										Computed: true,
+									},
+									"mode": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"gpu_profiles": {
+										Type:     schema.TypeList,
+										Computed: true,
+										Elem: &schema.Resource{
+											Schema: map[string]*schema.Schema{
+												"name": {
+													Type:     schema.TypeString,
+													Computed: true,
+												},
+												"description": {
+													Type:     schema.TypeString,
+													Computed: true,
+												},
+												"available_instances": {
+													Type:     schema.TypeInt,
+													Computed: true,
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+						"usb_device": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"address": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"vendor_id": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"product_id": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"manufacturer": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"product": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"serial": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"provider_override": &providerOverride,
+		},
+	}
+}
+
+func dataSourceHostDevicesRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	var hosts []rest.Host
+	if hostid, ok := d.GetOk("hostid"); ok {
+		host, err := client.GetHost(hostid.(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		hosts = append(hosts, host)
+		d.SetId(host.Hostid)
+	} else {
+		hosts, err = client.ListHosts("")
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		d.SetId("host_devices")
+	}
+
+	list := make([]interface{}, len(hosts))
+	for i := range hosts {
+		host := &hosts[i]
+		pci := hostPciDevices(host)
+		pciDevices := make([]interface{}, 0, len(pci))
+		for _, address := range sortedKeys(pci) {
+			pciDevices = append(pciDevices, pci[address])
+		}
+		usbDevices := make([]interface{}, len(host.Hardware.UsbDevices))
+		for j, dev := range host.Hardware.UsbDevices {
+			usbDevices[j] = map[string]interface{}{
+				"address":      usbAddress(dev.Busnum, dev.Devnum),
+				"vendor_id":    fmt.Sprintf("%04x", dev.IDVendor),
+				"product_id":   fmt.Sprintf("%04x", dev.IDProduct),
+				"manufacturer": dev.Manufacturer,
+				"product":      dev.Product,
+				"serial":       dev.Serial,
+			}
+		}
+		list[i] = map[string]interface{}{
+			"hostid":     host.Hostid,
+			"hostname":   host.Hostname,
+			"pci_device": pciDevices,
+			"usb_device": usbDevices,
+		}
+	}
+	sort.Slice(list, func(a, b int) bool {
+		return list[a].(map[string]interface{})["hostname"].(string) < list[b].(map[string]interface{})["hostname"].(string)
+	})
+	if err := d.Set("host", list); err != nil {
+		return diag.FromErr(err)
+	}
+	return diag.Diagnostics{}
+}
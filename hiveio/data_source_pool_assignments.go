@@ -0,0 +1,124 @@
+package hiveio
+
+import (
+	"context"
+	"net/url"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func dataSourcePoolAssignments() *schema.Resource {
+	return &schema.Resource{
+		Description: "Lists the users and groups assigned to pools and to the guests in them.",
+		ReadContext: dataSourcePoolAssignmentsRead,
+		Schema: map[string]*schema.Schema{
+			"pool_id": {
+				Type:        schema.TypeString,
+				Description: "Only list the assignments of this pool.",
+				Optional:    true,
+			},
+			"assignments": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"pool_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"guest_name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"realm": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"username": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"ad_group": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+			"provider_override": &providerOverride,
+		},
+	}
+}
+
+func dataSourcePoolAssignmentsRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	var pools []rest.Pool
+	var guests []rest.Guest
+	if poolID, ok := d.GetOk("pool_id"); ok {
+		pool, err := client.GetPool(poolID.(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		pools = append(pools, *pool)
+		guests, err = client.ListGuests("poolId=" + url.QueryEscape(pool.ID))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		d.SetId(pool.ID)
+	} else {
+		pools, err = client.ListGuestPools("")
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		guests, err = client.ListGuests("")
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		d.SetId("pool_assignments")
+	}
+
+	poolIDs := map[string]bool{}
+	assignments := []interface{}{}
+	for _, pool := range pools {
+		poolIDs[pool.ID] = true
+		if pool.Assignment == nil || (pool.Assignment.Username == "" && pool.Assignment.ADGroup == "") {
+			continue
+		}
+		assignments = append(assignments, map[string]interface{}{
+			"pool_id":    pool.ID,
+			"guest_name": "",
+			"realm":      pool.Assignment.Realm,
+			"username":   pool.Assignment.Username,
+			"ad_group":   pool.Assignment.ADGroup,
+		})
+	}
+	for _, guest := range guests {
+		if !poolIDs[guest.PoolID] || guest.Username == "" {
+			continue
+		}
+		assignments = append(assignments, map[string]interface{}{
+			"pool_id":    guest.PoolID,
+			"guest_name": guest.Name,
+			"realm":      guest.Realm,
+			"username":   guest.Username,
+			"ad_group":   guest.ADGroup,
+		})
+	}
+	sort.SliceStable(assignments, func(i, j int) bool {
+		a, b := assignments[i].(map[string]interface{}), assignments[j].(map[string]interface{})
+		if a["pool_id"] != b["pool_id"] {
+			return a["pool_id"].(string) < b["pool_id"].(string)
+		}
+		return a["guest_name"].(string) < b["guest_name"].(string)
+	})
+	if err := d.Set("assignments", assignments); err != nil {
+		return diag.FromErr(err)
+	}
+	return diag.Diagnostics{}
+}
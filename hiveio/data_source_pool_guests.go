@@ -0,0 +1,150 @@
+package hiveio
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourcePoolGuests() *schema.Resource {
+	return &schema.Resource{
+		Description: "Lists the guests of a guest pool or virtual machine with their runtime state.",
+		ReadContext: dataSourcePoolGuestsRead,
+		Schema: map[string]*schema.Schema{
+			"pool_id": {
+				Type:        schema.TypeString,
+				Description: "The id of the hiveio_guest_pool or hiveio_virtual_machine.",
+				Required:    true,
+			},
+			"state": {
+				Type:        schema.TypeList,
+				Description: "Only include guests in one of these states, for example ready.",
+				Optional:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"guests": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"state": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"host_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"hostname": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"template": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"realm": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"username": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"session_state": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"interface": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"network": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"vlan": {
+										Type:     schema.TypeInt,
+										Computed: true,
+									},
+									"ip_address": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"mac_address": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"provider_override": &providerOverride,
+		},
+	}
+}
+
+func dataSourcePoolGuestsRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	pool, err := client.GetPool(d.Get("pool_id").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	guests, err := listPoolGuests(client, pool.ID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	states := map[string]bool{}
+	for _, state := range d.Get("state").([]interface{}) {
+		states[state.(string)] = true
+	}
+
+	list := []interface{}{}
+	for _, guest := range guests {
+		if len(states) > 0 && !states[guest.GuestState] {
+			continue
+		}
+		interfaces := make([]interface{}, len(guest.Interfaces))
+		for i, iface := range guest.Interfaces {
+			interfaces[i] = map[string]interface{}{
+				"network":     iface.Network,
+				"vlan":        iface.Vlan,
+				"ip_address":  iface.IPAddress,
+				"mac_address": iface.MacAddress,
+			}
+		}
+		sessionState := ""
+		if guest.SessionInfo != nil {
+			sessionState = guest.SessionInfo.SessionState
+		}
+		list = append(list, map[string]interface{}{
+			"name":          guest.Name,
+			"state":         guest.GuestState,
+			"host_id":       guest.Hostid,
+			"hostname":      guest.Hostname,
+			"template":      guest.TemplateName,
+			"realm":         guest.Realm,
+			"username":      guest.Username,
+			"session_state": sessionState,
+			"interface":     interfaces,
+		})
+	}
+	if err := d.Set("guests", list); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(pool.ID)
+	return diag.Diagnostics{}
+}
@@ -0,0 +1,97 @@
+package hiveio
+
+import (
+	"context"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourcePools() *schema.Resource {
+	return &schema.Resource{
+		Description: "Lists the virtual machines and guest pools, optionally filtered by type and labels.",
+		ReadContext: dataSourcePoolsRead,
+		Schema: map[string]*schema.Schema{
+			"type": {
+				Type:         schema.TypeString,
+				Description:  "Only include pools of this type, standalone for virtual machines or vdi for guest pools.",
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice([]string{"standalone", "vdi"}, false),
+			},
+			"labels": labelsFilterSchema(),
+			"pools": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"type": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"description": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"labels": {
+							Type:     schema.TypeMap,
+							Computed: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+					},
+				},
+			},
+			"provider_override": &providerOverride,
+		},
+	}
+}
+
+func dataSourcePoolsRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	pools, err := client.ListGuestPools("")
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].Name < pools[j].Name
+	})
+
+	poolType := d.Get("type").(string)
+	filter := expandLabels(d.Get("labels"))
+	list := []interface{}{}
+	for _, pool := range pools {
+		if poolType != "" && pool.Type != poolType {
+			continue
+		}
+		labels, _ := tagLabels(pool.Tags)
+		if !labelsMatch(labels, filter) {
+			continue
+		}
+		list = append(list, map[string]interface{}{
+			"id":          pool.ID,
+			"name":        pool.Name,
+			"type":        pool.Type,
+			"description": pool.Description,
+			"labels":      labels,
+		})
+	}
+	if err := d.Set("pools", list); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId("pools")
+	return diag.Diagnostics{}
+}
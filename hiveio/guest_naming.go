@@ -0,0 +1,65 @@
+package hiveio
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+// netbiosMaxLength is the longest computer name windows guests accept
+const netbiosMaxLength = 15
+
+// guestCounterDigits is the minimum width of the counter Hive appends to the seed of a pool
+const guestCounterDigits = 3
+
+var netbiosSeedRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9-]*$`)
+
+// guestName returns the name Hive gives to guest number index of a pool: the seed in upper case
+// followed by the counter, zero padded to guestCounterDigits
+func guestName(seed string, index int) string {
+	return fmt.Sprintf("%s%0*d", strings.ToUpper(seed), guestCounterDigits, index)
+}
+
+// checkGuestNames checks that the names of the first maxGuests guests of a pool with seed are
+// valid NetBIOS names
+func checkGuestNames(seed string, maxGuests int) error {
+	if !netbiosSeedRegexp.MatchString(seed) {
+		return fmt.Errorf("seed %q must start with a letter or digit and only contain letters, digits and hyphens", seed)
+	}
+	if name := guestName(seed, max(maxGuests, 1)); len(name) > netbiosMaxLength {
+		return fmt.Errorf("guest names from seed %s are up to %d characters long, such as %s, NetBIOS names are limited to %d", seed, len(name), name, netbiosMaxLength)
+	}
+	return nil
+}
+
+// validateGuestNaming checks at plan time that the longest guest name of a pool with a windows
+// template fits in a NetBIOS name. The template is read when the cluster can be reached with the
+// provider configuration.
+func validateGuestNaming(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	for _, attr := range []string{"seed", "density", "schedule", "template"} {
+		if !d.NewValueKnown(attr) {
+			return nil
+		}
+	}
+	if _, ok := d.GetOk("provider_override"); ok || !d.HasChanges("seed", "density", "schedule", "template") {
+		return nil
+	}
+	client, ok := m.(*rest.Client)
+	if !ok {
+		return nil
+	}
+	template, err := client.GetTemplate(d.Get("template").(string))
+	if err != nil && strings.Contains(err.Error(), "\"error\": 404") {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read template %s: %w", d.Get("template").(string), err)
+	}
+	if !strings.HasPrefix(strings.ToLower(template.OS), "win") {
+		return nil
+	}
+	return checkGuestNames(d.Get("seed").(string), maxPoolDensity(d))
+}
@@ -0,0 +1,82 @@
+package hiveio
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func TestGuestName(t *testing.T) {
+	tests := []struct {
+		seed  string
+		index int
+		want  string
+	}{
+		{"win10", 1, "WIN10001"},
+		{"NYC-CAD-", 7, "NYC-CAD-007"},
+		{"DESK", 999, "DESK999"},
+		{"DESK", 1000, "DESK1000"},
+	}
+	for _, tt := range tests {
+		if got := guestName(tt.seed, tt.index); got != tt.want {
+			t.Errorf("guestName(%q, %d) = %q, want %q", tt.seed, tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestCheckGuestNames(t *testing.T) {
+	tests := []struct {
+		name      string
+		seed      string
+		maxGuests int
+		wantErr   string
+	}{
+		{"under the limit", "NYC-CAD-DSK", 999, ""},
+		{"at the limit", "NYC-CAD-DESK", 999, ""},
+		{"over the limit", "NYC-CAD-DESKS", 999, "up to 16 characters"},
+		{"counter grows past three digits", "NYC-CAD-DESK", 1000, "up to 16 characters"},
+		{"four digit counter at the limit", "NYC-CAD-DSK", 1000, ""},
+		{"empty density", "NYC-CAD-DESK", 0, ""},
+		{"invalid character", "NYC_CAD", 10, "only contain letters, digits and hyphens"},
+		{"leading hyphen", "-NYC", 10, "must start with a letter or digit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkGuestNames(tt.seed, tt.maxGuests)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestValidateGuestNaming checks that only pools with a windows template are held to NetBIOS limits
+func TestValidateGuestNaming(t *testing.T) {
+	hive, client := newFakeHive(t)
+	hive.templates["win10"] = rest.Template{Name: "win10", OS: "win10"}
+	hive.templates["ubuntu"] = rest.Template{Name: "ubuntu", OS: "linux"}
+
+	tests := []struct {
+		template string
+		seed     string
+		wantErr  bool
+	}{
+		{"win10", "DESKTOP-POOL", false},
+		{"win10", "DESKTOP-POOL-A", true},
+		{"ubuntu", "DESKTOP-POOL-A", false},
+		{"missing", "DESKTOP-POOL-A", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template+"/"+tt.seed, func(t *testing.T) {
+			config := `{"name": "desktops", "density": [1, 20], "profile": "default", "template": "` + tt.template + `", "seed": "` + tt.seed + `"}`
+			_, err := planResource(t, resourceGuestPool(), nil, config, client)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("expected error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package hiveio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func rolloutSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Description: "Rebuild the guests of a non-persistent pool in batches when the template changes instead of leaving it to the cluster. Guests built from the old template are deleted a batch at a time and the pool clones replacements from the new template.",
+		Optional:    true,
+		MaxItems:    1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"batch_size": {
+					Type:         schema.TypeInt,
+					Description:  "The number of guests to rebuild at a time.",
+					Default:      1,
+					Optional:     true,
+					ValidateFunc: validation.IntAtLeast(1),
+				},
+				"max_unavailable": {
+					Type:         schema.TypeInt,
+					Description:  "The most guests that may be rebuilding at once. Old guests that are not ready are replaced first and do not count against it.",
+					Default:      1,
+					Optional:     true,
+					ValidateFunc: validation.IntAtLeast(1),
+				},
+				"session_policy": {
+					Type:         schema.TypeString,
+					Description:  "What to do with guests that have a user logged in. skip leaves them on the old template, wait waits for the user to log off until session_timeout and then skips them, and force rebuilds them anyway.",
+					Default:      "wait",
+					Optional:     true,
+					ValidateFunc: validation.StringInSlice([]string{"skip", "wait", "force"}, false),
+				},
+				"session_timeout": {
+					Type:         schema.TypeString,
+					Description:  "How long to wait for users to log off with the wait session policy, for example 30m.",
+					Default:      "30m",
+					Optional:     true,
+					ValidateFunc: validateDuration,
+				},
+			},
+		},
+	}
+}
+
+func validateDuration(v interface{}, k string) ([]string, []error) {
+	if _, err := time.ParseDuration(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", k, err)}
+	}
+	return nil, nil
+}
+
+// validateRollout only allows rollouts on non-persistent pools, as rebuilding persistent guests loses user data
+func validateRollout(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if d.Get("rollout.#").(int) > 0 && d.Get("persistent").(bool) {
+		return errors.New("rollout can not be used with persistent pools")
+	}
+	return nil
+}
+
+type rolloutSettings struct {
+	batchSize      int
+	maxUnavailable int
+	sessionPolicy  string
+	sessionTimeout time.Duration
+}
+
+func getRolloutSettings(d *schema.ResourceData) rolloutSettings {
+	timeout, _ := time.ParseDuration(d.Get("rollout.0.session_timeout").(string))
+	return rolloutSettings{
+		batchSize:      d.Get("rollout.0.batch_size").(int),
+		maxUnavailable: d.Get("rollout.0.max_unavailable").(int),
+		sessionPolicy:  d.Get("rollout.0.session_policy").(string),
+		sessionTimeout: timeout,
+	}
+}
+
+// guestInSession is true while a user is logged in to the guest
+func guestInSession(guest rest.Guest) bool {
+	return guest.Username != ""
+}
+
+func listPoolGuests(client *rest.Client, poolID string) ([]rest.Guest, error) {
+	guests, err := client.ListGuests("poolId=" + url.QueryEscape(poolID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list guests for pool %s: %w", poolID, err)
+	}
+	var members []rest.Guest
+	for _, guest := range guests {
+		if guest.PoolID == poolID {
+			members = append(members, guest)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Name < members[j].Name
+	})
+	return members, nil
+}
+
+// rolloutGuestPool rebuilds the guests that are not built from template in batches. It returns
+// the guests that were skipped because of active sessions.
+func rolloutGuestPool(ctx context.Context, client *rest.Client, d *schema.ResourceData, template string, timeout time.Duration) ([]string, error) {
+	settings := getRolloutSettings(d)
+	deadline := time.Now().Add(timeout)
+	sessionDeadline := time.Now().Add(settings.sessionTimeout)
+	skipped := map[string]bool{}
+
+	for {
+		guests, err := listPoolGuests(client, d.Id())
+		if err != nil {
+			return nil, err
+		}
+		// stale guests that are not ready are replaced first without using the unavailable budget,
+		// which only counts the guests being rebuilt so a broken old guest cannot stall the rollout
+		var stale, unhealthy, waiting []rest.Guest
+		unavailable, ready := 0, 0
+		for _, guest := range guests {
+			if guest.TemplateName == template {
+				if guest.Error != nil && guest.Error.Message != "" {
+					return nil, fmt.Errorf("rollout aborted, guest %s failed: %s", guest.Name, guest.Error.Message)
+				}
+				if rest.IsGuestReady(guest) {
+					ready++
+				} else {
+					unavailable++
+				}
+				continue
+			}
+			if skipped[guest.Name] {
+				continue
+			}
+			if guestInSession(guest) && settings.sessionPolicy != "force" {
+				if settings.sessionPolicy == "skip" || time.Now().After(sessionDeadline) {
+					skipped[guest.Name] = true
+				} else {
+					waiting = append(waiting, guest)
+				}
+				continue
+			}
+			if rest.IsGuestReady(guest) {
+				stale = append(stale, guest)
+			} else {
+				unhealthy = append(unhealthy, guest)
+			}
+		}
+		if len(stale) == 0 && len(unhealthy) == 0 && len(waiting) == 0 {
+			return sortedKeys(skipped), nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out rolling out template %s, %d guests left", template, len(stale)+len(unhealthy)+len(waiting))
+		}
+
+		batch := max(0, min(settings.batchSize, settings.maxUnavailable-unavailable, len(stale)))
+		if batch == 0 && len(unhealthy) == 0 {
+			tflog.Info(ctx, "waiting to continue rollout", map[string]interface{}{
+				"pool_id":     d.Id(),
+				"unavailable": unavailable,
+				"in_session":  len(waiting),
+			})
+			if err := sleepContext(ctx, 10*time.Second); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var names []string
+		for _, guest := range append(unhealthy, stale[:batch]...) {
+			names = append(names, guest.Name)
+			if err := guest.Delete(client); err != nil {
+				return nil, fmt.Errorf("rollout aborted, failed to delete guest %s: %w", guest.Name, err)
+			}
+		}
+		tflog.Info(ctx, "rebuilding guests", map[string]interface{}{
+			"pool_id": d.Id(),
+			"guests":  strings.Join(names, ", "),
+			"ready":   ready,
+		})
+		if err := waitForRolloutBatch(ctx, client, d.Id(), template, ready+len(names), time.Until(deadline)); err != nil {
+			return nil, fmt.Errorf("rollout aborted while rebuilding %s: %w", strings.Join(names, ", "), err)
+		}
+	}
+}
+
+// waitForRolloutBatch waits for want guests built from template to be ready
+func waitForRolloutBatch(ctx context.Context, client *rest.Client, poolID, template string, want int, timeout time.Duration) error {
+	return retry.RetryContext(ctx, timeout, func() *retry.RetryError {
+		guests, err := listPoolGuests(client, poolID)
+		if err != nil {
+			return retry.NonRetryableError(err)
+		}
+		ready := 0
+		for _, guest := range guests {
+			if guest.Error != nil && guest.Error.Message != "" {
+				return retry.NonRetryableError(fmt.Errorf("guest %s failed: %s", guest.Name, guest.Error.Message))
+			}
+			if guest.TemplateName == template && rest.IsGuestReady(guest) {
+				ready++
+			}
+		}
+		if ready >= want {
+			return nil
+		}
+		if err := sleepContext(ctx, 10*time.Second); err != nil {
+			return retry.NonRetryableError(err)
+		}
+		return retry.RetryableError(fmt.Errorf("%d of %d guests ready on template %s", ready, want, template))
+	})
+}
+
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(duration):
+		return nil
+	}
+}
@@ -0,0 +1,188 @@
+package hiveio
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+var clockRegexp = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+func scheduleSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Description: "Time windows with their own density. Hive has no pool schedules, so the provider works out the density for the current time: the first matching window wins and density applies outside all windows. The result is shown in active_density, which changes in the plan when another window applies, so a scheduled terraform apply keeps the pool in step.",
+		Optional:    true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"days": {
+					Type:         schema.TypeString,
+					Description:  "The days of the week in cron syntax, 0 or 7 is Sunday. For example 1-5 for weekdays.",
+					Default:      "*",
+					Optional:     true,
+					ValidateFunc: validateCronDays,
+				},
+				"start": {
+					Type:         schema.TypeString,
+					Description:  "The start of the window as HH:MM.",
+					Required:     true,
+					ValidateFunc: validation.StringMatch(clockRegexp, "must be HH:MM"),
+				},
+				"end": {
+					Type:         schema.TypeString,
+					Description:  "The end of the window as HH:MM. A window that ends before it starts runs past midnight.",
+					Required:     true,
+					ValidateFunc: validation.StringMatch(clockRegexp, "must be HH:MM"),
+				},
+				"timezone": {
+					Type:         schema.TypeString,
+					Description:  "The timezone of start and end, for example America/Chicago.",
+					Default:      "UTC",
+					Optional:     true,
+					ValidateFunc: validateTimezone,
+				},
+				"density": {
+					Type:        schema.TypeList,
+					Description: "The minimum and maximum number of guests during the window.",
+					Required:    true,
+					MinItems:    2,
+					MaxItems:    2,
+					Elem: &schema.Schema{
+						Type: schema.TypeInt,
+					},
+				},
+			},
+		},
+	}
+}
+
+func validateTimezone(v interface{}, k string) ([]string, []error) {
+	if _, err := time.LoadLocation(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", k, err)}
+	}
+	return nil, nil
+}
+
+func validateCronDays(v interface{}, k string) ([]string, []error) {
+	if _, err := parseCronDays(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", k, err)}
+	}
+	return nil, nil
+}
+
+// parseCronDays parses a cron day of week field into the weekdays it matches
+func parseCronDays(field string) (map[time.Weekday]bool, error) {
+	days := map[time.Weekday]bool{}
+	for _, part := range strings.Split(field, ",") {
+		if part == "*" {
+			for day := time.Sunday; day <= time.Saturday; day++ {
+				days[day] = true
+			}
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(first)
+		if err != nil || from < 0 || from > 7 {
+			return nil, fmt.Errorf("invalid day %q, expected 0-7", first)
+		}
+		to := from
+		if isRange {
+			to, err = strconv.Atoi(last)
+			if err != nil || to < from || to > 7 {
+				return nil, fmt.Errorf("invalid day range %q", part)
+			}
+		}
+		for day := from; day <= to; day++ {
+			days[time.Weekday(day%7)] = true
+		}
+	}
+	return days, nil
+}
+
+// minutesOfDay converts HH:MM to minutes after midnight
+func minutesOfDay(clock string) int {
+	hours, _ := strconv.Atoi(clock[:2])
+	minutes, _ := strconv.Atoi(clock[3:])
+	return hours*60 + minutes
+}
+
+// scheduleWindowActive reports whether the window starting on the given days covers now
+func scheduleWindowActive(days, start, end, timezone string, now time.Time) bool {
+	weekdays, err := parseCronDays(days)
+	if err != nil {
+		return false
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return false
+	}
+	now = now.In(location)
+	minute := now.Hour()*60 + now.Minute()
+	from, to := minutesOfDay(start), minutesOfDay(end)
+	if from <= to {
+		return weekdays[now.Weekday()] && minute >= from && minute < to
+	}
+	// the window runs past midnight, the part after midnight belongs to the previous day
+	if minute >= from {
+		return weekdays[now.Weekday()]
+	}
+	return minute < to && weekdays[now.AddDate(0, 0, -1).Weekday()]
+}
+
+// resourceGetter is satisfied by both schema.ResourceData and schema.ResourceDiff
+type resourceGetter interface {
+	Get(string) interface{}
+}
+
+// scheduledDensity returns the density of the first schedule window that covers now, or density
+func scheduledDensity(d resourceGetter, now time.Time) []int {
+	for i := 0; i < d.Get("schedule.#").(int); i++ {
+		prefix := fmt.Sprintf("schedule.%d.", i)
+		if !scheduleWindowActive(d.Get(prefix+"days").(string), d.Get(prefix+"start").(string), d.Get(prefix+"end").(string), d.Get(prefix+"timezone").(string), now) {
+			continue
+		}
+		return intList(d.Get(prefix + "density").([]interface{}))
+	}
+	return intList(d.Get("density").([]interface{}))
+}
+
+// maxPoolDensity returns the largest number of guests the pool can have in any schedule window
+func maxPoolDensity(d resourceGetter) int {
+	maxGuests := 0
+	for _, density := range intList(d.Get("density").([]interface{})) {
+		maxGuests = max(maxGuests, density)
+	}
+	for i := 0; i < d.Get("schedule.#").(int); i++ {
+		for _, density := range intList(d.Get(fmt.Sprintf("schedule.%d.density", i)).([]interface{})) {
+			maxGuests = max(maxGuests, density)
+		}
+	}
+	return maxGuests
+}
+
+func intList(values []interface{}) []int {
+	ints := make([]int, len(values))
+	for i, value := range values {
+		ints[i] = value.(int)
+	}
+	return ints
+}
+
+// diffScheduledDensity plans a change to active_density when another schedule window applies
+func diffScheduledDensity(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("density") || !d.NewValueKnown("schedule") {
+		return d.SetNewComputed("active_density")
+	}
+	density := scheduledDensity(d, time.Now())
+	if slices.Equal(intList(d.Get("active_density").([]interface{})), density) {
+		return nil
+	}
+	return d.SetNew("active_density", density)
+}
@@ -0,0 +1,97 @@
+package hiveio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronDays(t *testing.T) {
+	tests := []struct {
+		field   string
+		want    []time.Weekday
+		wantErr bool
+	}{
+		{field: "*", want: []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}},
+		{field: "1-5", want: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}},
+		{field: "0", want: []time.Weekday{time.Sunday}},
+		{field: "7", want: []time.Weekday{time.Sunday}},
+		{field: "6-7", want: []time.Weekday{time.Sunday, time.Saturday}},
+		{field: "1,3,5", want: []time.Weekday{time.Monday, time.Wednesday, time.Friday}},
+		{field: "1-2,2-3", want: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday}},
+		{field: "", wantErr: true},
+		{field: "1,,3", wantErr: true},
+		{field: "8", wantErr: true},
+		{field: "-1", wantErr: true},
+		{field: "5-1", wantErr: true},
+		{field: "1-8", wantErr: true},
+		{field: "mon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got, err := parseCronDays(tt.field)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for _, day := range tt.want {
+				if !got[day] {
+					t.Fatalf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestScheduleWindowActive(t *testing.T) {
+	// 2026-10-16 is a Friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		days     string
+		start    string
+		end      string
+		timezone string
+		now      time.Time
+		want     bool
+	}{
+		{name: "inside", days: "1-5", start: "08:00", end: "18:00", now: at(16, 12, 0), want: true},
+		{name: "at the start", days: "1-5", start: "08:00", end: "18:00", now: at(16, 8, 0), want: true},
+		{name: "at the end", days: "1-5", start: "08:00", end: "18:00", now: at(16, 18, 0), want: false},
+		{name: "before", days: "1-5", start: "08:00", end: "18:00", now: at(16, 7, 59), want: false},
+		{name: "other day", days: "1-5", start: "08:00", end: "18:00", now: at(17, 12, 0), want: false},
+		{name: "empty window", days: "*", start: "08:00", end: "08:00", now: at(16, 8, 0), want: false},
+		{name: "invalid days", days: "", start: "08:00", end: "18:00", now: at(16, 12, 0), want: false},
+		{name: "invalid timezone", days: "*", start: "08:00", end: "18:00", timezone: "Mars/Olympus", now: at(16, 12, 0), want: false},
+		{name: "past midnight before midnight", days: "5", start: "22:00", end: "06:00", now: at(16, 23, 0), want: true},
+		{name: "past midnight after midnight", days: "5", start: "22:00", end: "06:00", now: at(17, 3, 0), want: true},
+		{name: "past midnight at the end", days: "5", start: "22:00", end: "06:00", now: at(17, 6, 0), want: false},
+		{name: "past midnight started the day before", days: "5", start: "22:00", end: "06:00", now: at(16, 3, 0), want: false},
+		{name: "past midnight on the next evening", days: "5", start: "22:00", end: "06:00", now: at(17, 23, 0), want: false},
+		{name: "past midnight into the week", days: "1-5", start: "22:00", end: "06:00", now: at(19, 3, 0), want: false},
+		{name: "past midnight out of the week", days: "1-5", start: "22:00", end: "06:00", now: at(17, 3, 0), want: true},
+		{name: "past midnight across sunday", days: "0", start: "22:00", end: "06:00", now: at(19, 3, 0), want: true},
+		{name: "timezone", days: "1-5", start: "08:00", end: "18:00", timezone: "America/Chicago", now: at(16, 22, 0), want: true},
+		{name: "timezone moves the day", days: "5", start: "20:00", end: "23:00", timezone: "America/Chicago", now: at(17, 2, 0), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timezone := tt.timezone
+			if timezone == "" {
+				timezone = "UTC"
+			}
+			if got := scheduleWindowActive(tt.days, tt.start, tt.end, timezone, tt.now); got != tt.want {
+				t.Fatalf("expected %t at %s", tt.want, tt.now.In(time.UTC).Format(time.RFC1123))
+			}
+		})
+	}
+}
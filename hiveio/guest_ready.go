@@ -0,0 +1,183 @@
+package hiveio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+var readyMethods = []string{"targetState", "ready", "ipAddress", "tcpPort", "guestAgent", "cloudInit", "brokerConnection"}
+
+// vmRestartAttributes are the attributes that rebuild the guest of a vm when changed
+var vmRestartAttributes = []string{
+	"cpu", "memory", "gpu", "firmware", "secure_boot", "display_driver", "os", "cdrom", "boot_order", "interface",
+	"cloudinit_enabled", "cloudinit_userdata", "cloudinit_networkconfig", "cloudinit",
+}
+
+func validateReadyMethod(val interface{}, key string) (warns []string, errs []error) {
+	v := val.(string)
+	if v == "cloudInit" {
+		warns = append(warns, fmt.Sprintf("%q: cloudInit is deprecated, use guestAgent. It waits for the guest agent, Hive does not report when cloud-init finishes", key))
+		return
+	}
+	for _, method := range readyMethods {
+		if v == method {
+			return
+		}
+	}
+	errs = append(errs, fmt.Errorf("%q must be one of %s", key, strings.Join(readyMethods, ", ")))
+	return
+}
+
+// validateReadyMethodSettings checks the settings each readiness check depends on at plan time
+func validateReadyMethodSettings(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.Get("wait_for_ready").(bool) {
+		return nil
+	}
+	switch d.Get("wait_for_ready_method").(string) {
+	case "tcpPort":
+		if d.Get("wait_for_ready_port").(int) == 0 {
+			return fmt.Errorf("wait_for_ready_port is required when wait_for_ready_method is tcpPort")
+		}
+	case "guestAgent", "cloudInit":
+		if !d.Get("inject_agent").(bool) {
+			return fmt.Errorf("wait_for_ready_method %s requires inject_agent", d.Get("wait_for_ready_method").(string))
+		}
+	case "brokerConnection":
+		if d.Get("broker_connection.#").(int) == 0 {
+			return fmt.Errorf("wait_for_ready_method brokerConnection requires a broker_connection")
+		}
+	}
+	return nil
+}
+
+// guestIPAddress returns the first address reported for a guest
+func guestIPAddress(guest rest.Guest) string {
+	for _, iface := range guest.Interfaces {
+		if iface.IPAddress != "" {
+			return iface.IPAddress
+		}
+	}
+	return ""
+}
+
+func guestHasIPAddress(guest rest.Guest) bool {
+	return guestIPAddress(guest) != ""
+}
+
+// guestAgentReported is true once the guest agent has reported in. Hive does not report the
+// cloud-init status of a guest, so this is what the guestAgent method and its deprecated cloudInit
+// name wait for. It only marks the end of provisioning when cloud-init starts the agent last.
+func guestAgentReported(guest rest.Guest) bool {
+	return guest.GuestState == "ready" && guest.AgentInstalled && guest.AgentVersion != ""
+}
+
+// brokerConnectionPort returns the port of the default broker connection, or the first connection
+func brokerConnectionPort(d *schema.ResourceData) int {
+	defaultConnection := d.Get("broker_default_connection").(string)
+	for i := 0; i < d.Get("broker_connection.#").(int); i++ {
+		prefix := fmt.Sprintf("broker_connection.%d.", i)
+		if defaultConnection == "" || d.Get(prefix+"name").(string) == defaultConnection {
+			return d.Get(prefix + "port").(int)
+		}
+	}
+	if d.Get("broker_connection.#").(int) > 0 {
+		return d.Get("broker_connection.0.port").(int)
+	}
+	return 0
+}
+
+// waitForGuestPort blocks until the guest has an ip address that accepts tcp connections on port
+func waitForGuestPort(ctx context.Context, client *rest.Client, guest *rest.Guest, port int, timeout time.Duration) error {
+	return retry.RetryContext(ctx, timeout, func() *retry.RetryError {
+		record, err := client.GetGuest(guest.Name)
+		if err != nil {
+			return retry.NonRetryableError(err)
+		}
+		ip := guestIPAddress(*record)
+		if ip == "" {
+			time.Sleep(5 * time.Second)
+			return retry.RetryableError(fmt.Errorf("waiting for %s to report an ip address", guest.Name))
+		}
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip, strconv.Itoa(port)), 5*time.Second)
+		if err != nil {
+			time.Sleep(5 * time.Second)
+			return retry.RetryableError(fmt.Errorf("waiting for %s to accept connections on port %d: %w", guest.Name, port, err))
+		}
+		conn.Close()
+		return nil
+	})
+}
+
+// waitForGuestRestart blocks until the guest that was running before an update has left the ready
+// state, so the readiness check that follows does not pass on the guest as it was before the update
+func waitForGuestRestart(ctx context.Context, client *rest.Client, previous *rest.Guest, timeout time.Duration) error {
+	return retry.RetryContext(ctx, timeout, func() *retry.RetryError {
+		guest, err := getPoolGuest(client, previous.PoolID)
+		if errors.Is(err, errGuestNotFound) {
+			return nil
+		} else if err != nil {
+			return retry.NonRetryableError(err)
+		}
+		if guest.Name != previous.Name || guest.UUID != previous.UUID || !rest.IsGuestReady(*guest) {
+			return nil
+		}
+		time.Sleep(5 * time.Second)
+		return retry.RetryableError(fmt.Errorf("waiting for %s to restart", guest.Name))
+	})
+}
+
+// waitForVMReady blocks until the guest of a vm passes the check from wait_for_ready_method. The
+// check gets the part of timeout left after the guest is found.
+func waitForVMReady(ctx context.Context, client *rest.Client, d *schema.ResourceData, pool *rest.Pool, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return retry.RetryContext(ctx, timeout, func() *retry.RetryError {
+		guest, err := getPoolGuest(client, pool.ID)
+		if err != nil {
+			if errors.Is(err, errGuestNotFound) {
+				time.Sleep(5 * time.Second)
+				return retry.RetryableError(fmt.Errorf("building pool %s", pool.ID))
+			}
+			return retry.NonRetryableError(err)
+		}
+		remaining := remainingTimeout(ctx, timeout)
+		method := d.Get("wait_for_ready_method").(string)
+		switch method {
+		case "targetState":
+			err = guest.WaitForGuestWithContext(ctx, client, remaining)
+		case "ready":
+			err = guest.WaitForGuestChange(ctx, client, remaining, rest.IsGuestReady)
+		case "ipAddress":
+			err = guest.WaitForGuestChange(ctx, client, remaining, guestHasIPAddress)
+		case "guestAgent", "cloudInit":
+			err = guest.WaitForGuestChange(ctx, client, remaining, guestAgentReported)
+		case "tcpPort":
+			err = waitForGuestPort(ctx, client, guest, d.Get("wait_for_ready_port").(int), remaining)
+		case "brokerConnection":
+			err = waitForGuestPort(ctx, client, guest, brokerConnectionPort(d), remaining)
+		}
+		if err != nil {
+			return retry.NonRetryableError(err)
+		}
+		return nil
+	})
+}
+
+// remainingTimeout returns the time left before the deadline of ctx, at most timeout, so nested
+// waits share the timeout of an operation instead of each getting all of it. The result is always
+// positive because a wait with no timeout never ends.
+func remainingTimeout(ctx context.Context, timeout time.Duration) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline))
+	}
+	return max(timeout, time.Millisecond)
+}
@@ -0,0 +1,79 @@
+package hiveio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestValidateReadyMethod(t *testing.T) {
+	tests := []struct {
+		method   string
+		wantWarn bool
+		wantErr  bool
+	}{
+		{method: "targetState"},
+		{method: "guestAgent"},
+		{method: "cloudInit", wantWarn: true},
+		{method: "booted", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			warns, errs := validateReadyMethod(tt.method, "wait_for_ready_method")
+			if (len(warns) > 0) != tt.wantWarn {
+				t.Errorf("expected a warning: %t, got %v", tt.wantWarn, warns)
+			}
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("expected an error: %t, got %v", tt.wantErr, errs)
+			}
+		})
+	}
+}
+
+func TestWaitForVMReady(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		agent   bool
+		wantErr bool
+	}{
+		{name: "agent reported", method: "guestAgent", agent: true},
+		{name: "deprecated name", method: "cloudInit", agent: true},
+		{name: "agent missing", method: "guestAgent", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hive, client := newFakeHive(t)
+			pool := testVMPool()
+			guest := testVMGuest()
+			if tt.agent {
+				guest.AgentInstalled, guest.AgentVersion = true, "8.0"
+			}
+			hive.pools[testVMID] = pool
+			hive.guests = append(hive.guests, guest)
+
+			d := schema.TestResourceDataRaw(t, resourceVM().Schema, map[string]interface{}{
+				"name":                  "kubuntu",
+				"wait_for_ready_method": tt.method,
+			})
+			// the nested wait gets what is left of the timeout, so a guest that never becomes
+			// ready fails after the timeout instead of waiting for the agent forever
+			start := time.Now()
+			err := waitForVMReady(context.Background(), client, d, &pool, 500*time.Millisecond)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected the wait to time out")
+				}
+				if elapsed := time.Since(start); elapsed > 5*time.Second {
+					t.Fatalf("the wait took %s, longer than its timeout", elapsed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package hiveio
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func rangeBoundSchema(description string) *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeInt,
+		Description:  description,
+		Optional:     true,
+		ValidateFunc: validateNonNegative,
+	}
+}
+
+func validateNonNegative(val interface{}, key string) (warns []string, errs []error) {
+	if val.(int) < 0 {
+		errs = append(errs, fmt.Errorf("%q must not be negative", key))
+	}
+	return
+}
+
+// guestProfileRange returns the [min, max] range sent to the api for attr. The bounds
+// default to the value of attr, and nil is returned when there is nothing to send.
+func guestProfileRange(d *schema.ResourceData, attr, minAttr, maxAttr string) []int {
+	base := d.Get(attr).(int)
+	lo, hi := d.Get(minAttr).(int), d.Get(maxAttr).(int)
+	if lo == 0 {
+		lo = base
+	}
+	if hi == 0 {
+		hi = base
+	}
+	if lo == 0 || hi == 0 {
+		return nil
+	}
+	return []int{lo, hi}
+}
+
+// setGuestProfileRange reads a [min, max] range back into attr and the bounds that are in use,
+// so bounds that only repeat attr do not show up as a diff
+func setGuestProfileRange(d *schema.ResourceData, attr, minAttr, maxAttr string, values []int) {
+	if len(values) == 0 {
+		return
+	}
+	lo, hi := values[0], values[len(values)-1]
+	minSet, maxSet := d.Get(minAttr).(int) != 0, d.Get(maxAttr).(int) != 0
+	switch {
+	case minSet && maxSet:
+		d.Set(minAttr, lo)
+		d.Set(maxAttr, hi)
+	case minSet:
+		d.Set(minAttr, lo)
+		d.Set(attr, hi)
+	case maxSet:
+		d.Set(attr, lo)
+		d.Set(maxAttr, hi)
+	default:
+		d.Set(attr, lo)
+		if hi != lo {
+			d.Set(maxAttr, hi)
+		}
+	}
+}
+
+// hostCapacity returns the most vcpus and memory in MB a single host in the cluster can provide
+func hostCapacity(client *rest.Client) (cpus int, memory int, err error) {
+	hosts, err := client.ListHosts("")
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, host := range hosts {
+		threads := host.Hardware.PhysicalCPUs * host.Hardware.PhysicalCoresPerCPU
+		if host.Hardware.HyperThreadingEnabled {
+			threads *= 2
+		}
+		if threads > cpus {
+			cpus = threads
+		}
+		if mem := host.Hardware.TotalPhysicalMemory / 1024 / 1024; mem > memory {
+			memory = mem
+		}
+	}
+	return cpus, memory, nil
+}
+
+func validateRange(d *schema.ResourceDiff, attr, minAttr, maxAttr string, capacity int) error {
+	base := d.Get(attr).(int)
+	lo, hi := d.Get(minAttr).(int), d.Get(maxAttr).(int)
+	if base == 0 && (lo == 0) != (hi == 0) {
+		return fmt.Errorf("%s is required when only one of %s and %s is set", attr, minAttr, maxAttr)
+	}
+	if lo == 0 {
+		lo = base
+	}
+	if hi == 0 {
+		hi = base
+	}
+	if lo > hi {
+		return fmt.Errorf("%s %d is greater than %s %d", minAttr, lo, maxAttr, hi)
+	}
+	if capacity > 0 && hi > capacity {
+		name := maxAttr
+		if d.Get(maxAttr).(int) == 0 {
+			name = attr
+		}
+		return fmt.Errorf("%s %d is more than the largest host provides (%d)", name, hi, capacity)
+	}
+	return nil
+}
+
+// validateGuestResources checks cpu and memory ranges at plan time, including against host
+// capacity when the cluster can be reached with the provider configuration
+func validateGuestResources(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	for _, attr := range []string{"cpu", "cpu_min", "cpu_max", "memory", "memory_min", "memory_max"} {
+		if !d.NewValueKnown(attr) {
+			return nil
+		}
+	}
+	var cpus, memory int
+	if _, ok := d.GetOk("provider_override"); !ok && d.HasChanges("cpu", "cpu_min", "cpu_max", "memory", "memory_min", "memory_max") {
+		if client, ok := m.(*rest.Client); ok {
+			var err error
+			cpus, memory, err = hostCapacity(client)
+			if err != nil {
+				return fmt.Errorf("failed to read host capacity: %w", err)
+			}
+		}
+	}
+	if err := validateRange(d, "cpu", "cpu_min", "cpu_max", cpus); err != nil {
+		return err
+	}
+	return validateRange(d, "memory", "memory_min", "memory_max", memory)
+}
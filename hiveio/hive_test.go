@@ -0,0 +1,388 @@
+package hiveio
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	ctyjson "github.com/hashicorp/go-cty/cty/json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+// fakeHive is an in memory Hive api for unit tests. Tests add records to it and inspect the
+// requests the provider sent.
+type fakeHive struct {
+	t         *testing.T
+	mu        sync.Mutex
+	mux       *http.ServeMux
+	pools     map[string]rest.Pool
+	guests    []rest.Guest
+	templates map[string]rest.Template
+	hosts     []rest.Host
+	// files holds the disk files of the storage pools as storage id/filename
+	files map[string]bool
+	tasks map[string]rest.Task
+	// failCopies fails the copies to the listed target filenames with the message
+	failCopies map[string]string
+	// templateState is the state of templates after they are created, available when empty
+	templateState string
+	// changed is closed and replaced whenever a record changes, waking up the change feeds
+	changed  chan struct{}
+	requests []string
+}
+
+// newFakeHive starts a fake Hive api and returns it with a client connected to it
+func newFakeHive(t *testing.T) (*fakeHive, *rest.Client) {
+	t.Helper()
+	hive := &fakeHive{
+		t:         t,
+		mux:       http.NewServeMux(),
+		pools:     make(map[string]rest.Pool),
+		templates: make(map[string]rest.Template),
+		files:     make(map[string]bool),
+		tasks:     make(map[string]rest.Task),
+		changed:   make(chan struct{}),
+	}
+	hive.mux.HandleFunc("GET /api/pool/{id}", func(w http.ResponseWriter, r *http.Request) {
+		pool, ok := hive.pool(r.PathValue("id"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		hive.writeJSON(w, pool)
+	})
+	hive.mux.HandleFunc("PUT /api/pool/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var pool rest.Pool
+		if !hive.readJSON(w, r, &pool) {
+			return
+		}
+		hive.mu.Lock()
+		defer hive.mu.Unlock()
+		if _, ok := hive.pools[r.PathValue("id")]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		hive.pools[r.PathValue("id")] = pool
+	})
+	hive.mux.HandleFunc("DELETE /api/pool/{id}", func(w http.ResponseWriter, r *http.Request) {
+		hive.mu.Lock()
+		defer hive.mu.Unlock()
+		delete(hive.pools, r.PathValue("id"))
+	})
+	hive.mux.HandleFunc("GET /api/pools", func(w http.ResponseWriter, r *http.Request) {
+		hive.mu.Lock()
+		pools := []rest.Pool{}
+		for _, pool := range hive.pools {
+			if name := r.URL.Query().Get("name"); name == "" || pool.Name == name {
+				pools = append(pools, pool)
+			}
+		}
+		hive.mu.Unlock()
+		hive.writeJSON(w, pools)
+	})
+	hive.mux.HandleFunc("POST /api/pools", func(w http.ResponseWriter, r *http.Request) {
+		var pool rest.Pool
+		if !hive.readJSON(w, r, &pool) {
+			return
+		}
+		pool.ID = uuid.NewString()
+		hive.mu.Lock()
+		hive.pools[pool.ID] = pool
+		hive.mu.Unlock()
+		hive.writeJSON(w, map[string]string{"id": pool.ID})
+	})
+	hive.mux.HandleFunc("GET /api/guests", func(w http.ResponseWriter, r *http.Request) {
+		hive.mu.Lock()
+		guests := []rest.Guest{}
+		for _, guest := range hive.guests {
+			if poolID := r.URL.Query().Get("poolId"); poolID == "" || guest.PoolID == poolID {
+				guests = append(guests, guest)
+			}
+		}
+		hive.mu.Unlock()
+		hive.writeJSON(w, guests)
+	})
+	hive.mux.HandleFunc("GET /api/guest/{name}", func(w http.ResponseWriter, r *http.Request) {
+		hive.mu.Lock()
+		defer hive.mu.Unlock()
+		for _, guest := range hive.guests {
+			if guest.Name == r.PathValue("name") {
+				hive.writeJSON(w, guest)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	hive.mux.HandleFunc("POST /api/guest/{name}/shutdown", func(w http.ResponseWriter, r *http.Request) {
+		if !hive.updateGuest(r.PathValue("name"), func(guest *rest.Guest) { guest.GuestState = "off" }) {
+			http.NotFound(w, r)
+		}
+	})
+	hive.mux.HandleFunc("GET /socket.io/", hive.serveChangeFeed)
+	hive.mux.HandleFunc("GET /api/template/{name}", func(w http.ResponseWriter, r *http.Request) {
+		hive.mu.Lock()
+		template, ok := hive.templates[r.PathValue("name")]
+		hive.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		hive.writeJSON(w, template)
+	})
+	hive.mux.HandleFunc("POST /api/templates", func(w http.ResponseWriter, r *http.Request) {
+		var template rest.Template
+		if !hive.readJSON(w, r, &template) {
+			return
+		}
+		hive.mu.Lock()
+		defer hive.mu.Unlock()
+		template.State = hive.templateState
+		if template.State == "" {
+			template.State = "available"
+		}
+		hive.templates[template.Name] = template
+	})
+	hive.mux.HandleFunc("DELETE /api/template/{name}", func(w http.ResponseWriter, r *http.Request) {
+		hive.mu.Lock()
+		defer hive.mu.Unlock()
+		delete(hive.templates, r.PathValue("name"))
+	})
+	hive.mux.HandleFunc("GET /api/storage/pool/{id}", func(w http.ResponseWriter, r *http.Request) {
+		hive.writeJSON(w, rest.StoragePool{ID: r.PathValue("id"), Name: r.PathValue("id")})
+	})
+	hive.mux.HandleFunc("POST /api/storage/pool/{id}/diskInfo", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			FilePath string `json:"filePath"`
+		}
+		if !hive.readJSON(w, r, &body) {
+			return
+		}
+		if !hive.hasFile(r.PathValue("id"), body.FilePath) {
+			http.NotFound(w, r)
+			return
+		}
+		hive.writeJSON(w, rest.DiskInfo{Filename: body.FilePath, Format: "qcow2", VirtualSize: 20 << 30})
+	})
+	hive.mux.HandleFunc("DELETE /api/storage/pool/{id}/{filename}", func(w http.ResponseWriter, r *http.Request) {
+		hive.mu.Lock()
+		defer hive.mu.Unlock()
+		key := r.PathValue("id") + "/" + r.PathValue("filename")
+		if !hive.files[key] {
+			http.NotFound(w, r)
+			return
+		}
+		delete(hive.files, key)
+		hive.writeJSON(w, map[string]bool{"deleted": true})
+	})
+	hive.mux.HandleFunc("POST /api/template/convert", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			DstStorage  string `json:"dstStorage"`
+			DstFilename string `json:"dstFilename"`
+		}
+		if !hive.readJSON(w, r, &body) {
+			return
+		}
+		hive.writeJSON(w, map[string]string{"taskId": hive.copyFile(body.DstStorage, body.DstFilename)})
+	})
+	hive.mux.HandleFunc("POST /api/storage/pool/{id}/createDisk", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Filename string `json:"filename"`
+		}
+		if !hive.readJSON(w, r, &body) {
+			return
+		}
+		hive.writeJSON(w, map[string]string{"taskId": hive.copyFile(r.PathValue("id"), body.Filename)})
+	})
+	hive.mux.HandleFunc("GET /api/task/{id}", func(w http.ResponseWriter, r *http.Request) {
+		hive.mu.Lock()
+		task, ok := hive.tasks[r.PathValue("id")]
+		hive.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		hive.writeJSON(w, task)
+	})
+	hive.mux.HandleFunc("GET /api/hosts", func(w http.ResponseWriter, r *http.Request) {
+		hive.mu.Lock()
+		hosts := append([]rest.Host{}, hive.hosts...)
+		hive.mu.Unlock()
+		hive.writeJSON(w, hosts)
+	})
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hive.mu.Lock()
+		hive.requests = append(hive.requests, r.Method+" "+r.URL.Path)
+		hive.mu.Unlock()
+		hive.mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hive, &rest.Client{Host: host, Port: uint(portNumber), AllowInsecure: true}
+}
+
+func (hive *fakeHive) pool(id string) (rest.Pool, bool) {
+	hive.mu.Lock()
+	defer hive.mu.Unlock()
+	pool, ok := hive.pools[id]
+	return pool, ok
+}
+
+// poolByName returns the pool named name, failing the test when there is none
+func (hive *fakeHive) poolByName(name string) rest.Pool {
+	hive.t.Helper()
+	hive.mu.Lock()
+	defer hive.mu.Unlock()
+	for _, pool := range hive.pools {
+		if pool.Name == name {
+			return pool
+		}
+	}
+	hive.t.Fatalf("pool %s was not created", name)
+	return rest.Pool{}
+}
+
+// updateGuest changes the guest named name and notifies the change feeds
+func (hive *fakeHive) updateGuest(name string, update func(*rest.Guest)) bool {
+	hive.mu.Lock()
+	defer hive.mu.Unlock()
+	for i := range hive.guests {
+		if hive.guests[i].Name == name {
+			update(&hive.guests[i])
+			close(hive.changed)
+			hive.changed = make(chan struct{})
+			return true
+		}
+	}
+	return false
+}
+
+// serveChangeFeed sends the guest or task a change feed is registered for when it registers and
+// after every change
+func (hive *fakeHive) serveChangeFeed(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	_, message, err := conn.ReadMessage()
+	if err != nil || !strings.HasPrefix(string(message), "42") {
+		return
+	}
+	var register []json.RawMessage
+	var options struct {
+		Table  string            `json:"table"`
+		Filter map[string]string `json:"filter"`
+	}
+	if json.Unmarshal(message[2:], &register) != nil || len(register) < 2 || json.Unmarshal(register[1], &options) != nil {
+		return
+	}
+	closed := make(chan struct{})
+	go func() {
+		// drain the keep alive and close messages so a closed feed ends the handler
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	for {
+		hive.mu.Lock()
+		var record interface{}
+		switch options.Table {
+		case "guest":
+			for _, guest := range hive.guests {
+				if guest.Name == options.Filter["name"] {
+					record = guest
+				}
+			}
+		case "task":
+			if task, ok := hive.tasks[options.Filter["id"]]; ok {
+				record = task
+			}
+		}
+		changed := hive.changed
+		hive.mu.Unlock()
+		if record != nil {
+			change, _ := json.Marshal([]interface{}{"change", options.Table, map[string]interface{}{"new_val": record}})
+			if conn.WriteMessage(websocket.TextMessage, append([]byte("42"), change...)) != nil {
+				return
+			}
+		}
+		select {
+		case <-changed:
+		case <-closed:
+			return
+		}
+	}
+}
+
+func (hive *fakeHive) hasFile(storageID, filename string) bool {
+	hive.mu.Lock()
+	defer hive.mu.Unlock()
+	return hive.files[storageID+"/"+filename]
+}
+
+// copyFile creates filename in a storage pool and returns the id of the finished task, which
+// failed without creating the file when the filename is in failCopies
+func (hive *fakeHive) copyFile(storageID, filename string) string {
+	hive.mu.Lock()
+	defer hive.mu.Unlock()
+	task := rest.Task{ID: uuid.NewString(), State: "completed"}
+	if message, ok := hive.failCopies[filename]; ok {
+		task.State = "failed"
+		task.Message = message
+	} else {
+		hive.files[storageID+"/"+filename] = true
+	}
+	hive.tasks[task.ID] = task
+	return task.ID
+}
+
+func (hive *fakeHive) writeJSON(w http.ResponseWriter, v interface{}) {
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		hive.t.Errorf("failed to encode response: %s", err)
+	}
+}
+
+func (hive *fakeHive) readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// planResource plans config, a json object of resource attributes, against state the way terraform
+// does, running the CustomizeDiff functions of r with meta
+func planResource(t *testing.T, r *schema.Resource, state *terraform.InstanceState, config string, meta interface{}) (*terraform.InstanceDiff, error) {
+	t.Helper()
+	schemaBlock := r.CoreConfigSchema()
+	raw, err := ctyjson.Unmarshal([]byte(config), schemaBlock.ImpliedType())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state == nil {
+		state = &terraform.InstanceState{}
+	}
+	state.RawConfig = raw
+	return r.Diff(context.Background(), state, terraform.NewResourceConfigShimmed(raw, schemaBlock), meta)
+}
@@ -0,0 +1,477 @@
+package hiveio
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+var hexIDRegexp = regexp.MustCompile(`^[0-9a-fA-F]{4}$`)
+var pciAddressRegexp = regexp.MustCompile(`^([0-9a-fA-F]{4}):([0-9a-fA-F]{2}):([0-9a-fA-F]{2})\.([0-7])$`)
+var usbAddressRegexp = regexp.MustCompile(`^([0-9]{1,3}):([0-9]{1,3})$`)
+
+func gpuProfileSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeString,
+		Description: "The vGPU (mdev) profile to give the guest, for example nvidia-63. Requires gpu to be enabled.",
+		Optional:    true,
+	}
+}
+
+func pciDeviceSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Description: "A pci device to pass through to the guest, matched by vendor and device id or by host address.",
+		Optional:    true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"vendor_id": {
+					Type:         schema.TypeString,
+					Description:  "The vendor id in hex, for example 10de.",
+					Optional:     true,
+					ValidateFunc: validation.StringMatch(hexIDRegexp, "must be a 4 digit hex id"),
+				},
+				"device_id": {
+					Type:         schema.TypeString,
+					Description:  "The device id in hex.",
+					Optional:     true,
+					ValidateFunc: validation.StringMatch(hexIDRegexp, "must be a 4 digit hex id"),
+				},
+				"address": {
+					Type:         schema.TypeString,
+					Description:  "The pci address on the host in domain:bus:slot.function format, for example 0000:3b:00.0.",
+					Optional:     true,
+					ValidateFunc: validation.StringMatch(pciAddressRegexp, "must be in domain:bus:slot.function format"),
+				},
+			},
+		},
+	}
+}
+
+func usbDeviceSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Description: "A usb device to pass through to the guest, matched on each host by vendor and product id, and serial when set, or by host address. Hive passes usb devices through by bus and device number, so the guest can only run on the hosts where the matched devices have the same addresses.",
+		Optional:    true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"vendor_id": {
+					Type:         schema.TypeString,
+					Description:  "The vendor id in hex.",
+					Optional:     true,
+					ValidateFunc: validation.StringMatch(hexIDRegexp, "must be a 4 digit hex id"),
+				},
+				"product_id": {
+					Type:         schema.TypeString,
+					Description:  "The product id in hex.",
+					Optional:     true,
+					ValidateFunc: validation.StringMatch(hexIDRegexp, "must be a 4 digit hex id"),
+				},
+				"serial": {
+					Type:        schema.TypeString,
+					Description: "The serial number, to tell apart devices with the same vendor and product id.",
+					Optional:    true,
+				},
+				"address": {
+					Type:         schema.TypeString,
+					Description:  "The usb address on the host in bus:device format, for example 1:4.",
+					Optional:     true,
+					ValidateFunc: validation.StringMatch(usbAddressRegexp, "must be in bus:device format"),
+				},
+			},
+		},
+	}
+}
+
+// validateHostDevices checks that devices can be matched at plan time
+func validateHostDevices(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if d.Get("gpu_profile").(string) != "" && !d.Get("gpu").(bool) {
+		return fmt.Errorf("gpu_profile requires gpu to be enabled")
+	}
+	for i := 0; i < d.Get("usb_device.#").(int); i++ {
+		prefix := fmt.Sprintf("usb_device.%d.", i)
+		if d.Get(prefix+"serial").(string) != "" && d.Get(prefix+"address").(string) != "" {
+			return fmt.Errorf("usb_device.%d: address conflicts with serial", i)
+		}
+	}
+	for _, block := range []struct{ name, product string }{{"pci_device", "device_id"}, {"usb_device", "product_id"}} {
+		for i := 0; i < d.Get(block.name+".#").(int); i++ {
+			prefix := fmt.Sprintf("%s.%d.", block.name, i)
+			address := d.Get(prefix + "address").(string)
+			vendor := d.Get(prefix + "vendor_id").(string)
+			product := d.Get(prefix + block.product).(string)
+			if address != "" && (vendor != "" || product != "") {
+				return fmt.Errorf("%s.%d: address conflicts with vendor_id and %s", block.name, i, block.product)
+			}
+			if address == "" && (vendor == "" || product == "") {
+				return fmt.Errorf("%s.%d: address or both vendor_id and %s are required", block.name, i, block.product)
+			}
+		}
+	}
+	return nil
+}
+
+// validatePoolHostDevices rejects passthrough devices on pools with more than one guest, a pci or
+// usb device can only be given to one guest at a time
+func validatePoolHostDevices(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("density") || !d.NewValueKnown("schedule") {
+		return nil
+	}
+	if maxGuests := maxPoolDensity(d); maxGuests > 1 {
+		for _, name := range []string{"pci_device", "usb_device"} {
+			if d.Get(name+".#").(int) > 0 {
+				return fmt.Errorf("%s cannot be used with a density of %d, a device can only be passed through to one guest", name, maxGuests)
+			}
+		}
+	}
+	return nil
+}
+
+func parseHexID(id string) int {
+	v, _ := strconv.ParseInt(id, 16, 32)
+	return int(v)
+}
+
+func pciAddress(domain, bus, slot, fn int) string {
+	return fmt.Sprintf("%04x:%02x:%02x.%x", domain, bus, slot, fn)
+}
+
+func usbAddress(bus, device int) string {
+	return fmt.Sprintf("%d:%d", bus, device)
+}
+
+// hostPciDevices returns the pci devices and video cards of a host keyed by address
+func hostPciDevices(host *rest.Host) map[string]map[string]interface{} {
+	devices := map[string]map[string]interface{}{}
+	add := func(domain, bus, slot, fn, vendor, device, class, iommu int, mode string, profiles []interface{}) {
+		address := pciAddress(domain, bus, slot, fn)
+		if _, ok := devices[address]; ok {
+			return
+		}
+		devices[address] = map[string]interface{}{
+			"address":      address,
+			"vendor_id":    fmt.Sprintf("%04x", vendor),
+			"device_id":    fmt.Sprintf("%04x", device),
+			"device_class": class,
+			"iommu_group":  iommu,
+			"mode":         mode,
+			"gpu_profiles": profiles,
+		}
+	}
+	for _, dev := range host.Hardware.PciDevices {
+		profiles := []interface{}{}
+		for _, name := range sortedKeys(dev.MdevSupportedTypes) {
+			mdev := dev.MdevSupportedTypes[name]
+			profiles = append(profiles, map[string]interface{}{
+				"name":                name,
+				"description":         mdev.Description,
+				"available_instances": mdev.AvailableInstances,
+			})
+		}
+		add(dev.Domain, dev.Bus, dev.Slot, dev.Func, dev.VendorID, dev.DeviceID, dev.DeviceClass, dev.IommuGroup, dev.Mode, profiles)
+	}
+	for _, dev := range host.Hardware.VideoCards {
+		profiles := []interface{}{}
+		for _, name := range sortedKeys(dev.MdevSupportedTypes) {
+			mdev := dev.MdevSupportedTypes[name]
+			profiles = append(profiles, map[string]interface{}{
+				"name":                name,
+				"description":         mdev.Description,
+				"available_instances": mdev.AvailableInstances,
+			})
+		}
+		add(dev.Domain, dev.Bus, dev.Slot, dev.Func, dev.VendorID, dev.DeviceID, dev.DeviceClass, dev.IommuGroup, dev.Mode, profiles)
+	}
+	return devices
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hostHasGpuProfile reports whether any device on the host has instances of an mdev type left
+func hostHasGpuProfile(host *rest.Host, profile string) bool {
+	for _, dev := range hostPciDevices(host) {
+		for _, p := range dev["gpu_profiles"].([]interface{}) {
+			p := p.(map[string]interface{})
+			if p["name"] == profile && p["available_instances"].(int) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchHostDevices resolves the device blocks against a single host. ok is false when the
+// host does not have every requested device.
+func matchHostDevices(d *schema.ResourceData, host *rest.Host) (devices []*rest.HostDevice, ok bool) {
+	pci := hostPciDevices(host)
+	for i := 0; i < d.Get("pci_device.#").(int); i++ {
+		prefix := fmt.Sprintf("pci_device.%d.", i)
+		address := d.Get(prefix + "address").(string)
+		if address == "" {
+			vendor := fmt.Sprintf("%04x", parseHexID(d.Get(prefix+"vendor_id").(string)))
+			product := fmt.Sprintf("%04x", parseHexID(d.Get(prefix+"device_id").(string)))
+			for _, dev := range pci {
+				if dev["vendor_id"] == vendor && dev["device_id"] == product && !deviceTaken(devices, "pci", dev["address"].(string)) {
+					if address == "" || dev["address"].(string) < address {
+						address = dev["address"].(string)
+					}
+				}
+			}
+		}
+		match := pciAddressRegexp.FindStringSubmatch(strings.ToLower(address))
+		if match == nil {
+			return nil, false
+		}
+		if _, found := pci[strings.ToLower(address)]; !found {
+			return nil, false
+		}
+		devices = append(devices, &rest.HostDevice{
+			Type:    "pci",
+			Managed: true,
+			Domain:  parseHexID(match[1]),
+			Bus:     parseHexID(match[2]),
+			Slot:    parseHexID(match[3]),
+			Func:    parseHexID(match[4]),
+		})
+	}
+	for i := 0; i < d.Get("usb_device.#").(int); i++ {
+		prefix := fmt.Sprintf("usb_device.%d.", i)
+		address := d.Get(prefix + "address").(string)
+		vendor := parseHexID(d.Get(prefix + "vendor_id").(string))
+		product := parseHexID(d.Get(prefix + "product_id").(string))
+		serial := d.Get(prefix + "serial").(string)
+		var bus, device int
+		found := false
+		for _, dev := range host.Hardware.UsbDevices {
+			if address != "" && usbAddress(dev.Busnum, dev.Devnum) != address {
+				continue
+			}
+			if address == "" && (dev.IDVendor != vendor || dev.IDProduct != product || (serial != "" && dev.Serial != serial)) {
+				continue
+			}
+			if deviceTaken(devices, "usb", usbAddress(dev.Busnum, dev.Devnum)) {
+				continue
+			}
+			bus, device, found = dev.Busnum, dev.Devnum, true
+			break
+		}
+		if !found {
+			return nil, false
+		}
+		devices = append(devices, &rest.HostDevice{Type: "usb", Bus: bus, Device: device})
+	}
+	if profile := d.Get("gpu_profile").(string); profile != "" {
+		if !hostHasGpuProfile(host, profile) {
+			return nil, false
+		}
+		devices = append(devices, &rest.HostDevice{Type: "mdev", Model: "vfio-pci", MdevType: profile})
+	}
+	return devices, true
+}
+
+func deviceTaken(devices []*rest.HostDevice, deviceType, address string) bool {
+	for _, dev := range devices {
+		if dev.Type != deviceType {
+			continue
+		}
+		if deviceType == "pci" && pciAddress(dev.Domain, dev.Bus, dev.Slot, dev.Func) == address {
+			return true
+		}
+		if deviceType == "usb" && usbAddress(dev.Bus, dev.Device) == address {
+			return true
+		}
+	}
+	return false
+}
+
+func sameHostDevices(a, b []*rest.HostDevice) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if *a[i] != *b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// applyHostDevices resolves gpu_profile, pci_device and usb_device against the hosts in the
+// cluster. The devices are added to the guest profile and the pool affinity is narrowed to
+// the hosts that have the same devices.
+func applyHostDevices(client *rest.Client, d *schema.ResourceData, pool *rest.Pool) error {
+	if !hostDevicesRequested(d) {
+		return nil
+	}
+	hosts, err := client.ListHosts("")
+	if err != nil {
+		return fmt.Errorf("failed to list hosts: %w", err)
+	}
+	allowed := map[string]bool{}
+	for _, hostid := range pool.PoolAffinity.AllowedHostIDs {
+		allowed[hostid] = true
+	}
+
+	// the devices are matched on each host, hosts where they resolve to the same addresses can
+	// share the guest profile and the largest of those groups is used
+	type hostGroup struct {
+		devices []*rest.HostDevice
+		hostIDs []string
+	}
+	var groups []*hostGroup
+	for i := range hosts {
+		host := &hosts[i]
+		if len(allowed) > 0 && !allowed[host.Hostid] {
+			continue
+		}
+		matched, ok := matchHostDevices(d, host)
+		if !ok {
+			continue
+		}
+		var group *hostGroup
+		for _, g := range groups {
+			if sameHostDevices(g.devices, matched) {
+				group = g
+				break
+			}
+		}
+		if group == nil {
+			group = &hostGroup{devices: matched}
+			groups = append(groups, group)
+		}
+		group.hostIDs = append(group.hostIDs, host.Hostid)
+	}
+	if len(groups) == 0 {
+		return fmt.Errorf("no allowed host has all of the requested devices for %s", pool.Name)
+	}
+	best := groups[0]
+	for _, group := range groups[1:] {
+		if len(group.hostIDs) > len(best.hostIDs) {
+			best = group
+		}
+	}
+	pool.GuestProfile.HostDevices = best.devices
+	pool.PoolAffinity.AllowedHostIDs = best.hostIDs
+	return nil
+}
+
+// readHostDevices rebuilds the pci_device and usb_device blocks from the devices of a pool. Blocks
+// configured by vendor and product id are looked up on the allowed hosts, so a device that was
+// changed or is no longer present shows up in the plan.
+func readHostDevices(client *rest.Client, d *schema.ResourceData, pool *rest.Pool) (pciDevices, usbDevices []interface{}, err error) {
+	pciDevices, usbDevices = []interface{}{}, []interface{}{}
+	var poolPci, poolUsb []*rest.HostDevice
+	for _, dev := range pool.GuestProfile.HostDevices {
+		switch dev.Type {
+		case "pci":
+			poolPci = append(poolPci, dev)
+		case "usb":
+			poolUsb = append(poolUsb, dev)
+		}
+	}
+	if len(poolPci) == 0 && len(poolUsb) == 0 {
+		return pciDevices, usbDevices, nil
+	}
+	hosts, err := client.ListHosts("")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list hosts: %w", err)
+	}
+	allowed := map[string]bool{}
+	if pool.PoolAffinity != nil {
+		for _, hostid := range pool.PoolAffinity.AllowedHostIDs {
+			allowed[hostid] = true
+		}
+	}
+
+	for i, dev := range poolPci {
+		address := pciAddress(dev.Domain, dev.Bus, dev.Slot, dev.Func)
+		block := map[string]interface{}{"address": address, "vendor_id": "", "device_id": ""}
+		prefix := fmt.Sprintf("pci_device.%d.", i)
+		if i < d.Get("pci_device.#").(int) && d.Get(prefix+"address").(string) == "" {
+			for j := range hosts {
+				if len(allowed) > 0 && !allowed[hosts[j].Hostid] {
+					continue
+				}
+				if info, ok := hostPciDevices(&hosts[j])[address]; ok {
+					block = map[string]interface{}{
+						"address":   "",
+						"vendor_id": configuredHexID(d.Get(prefix+"vendor_id").(string), info["vendor_id"].(string)),
+						"device_id": configuredHexID(d.Get(prefix+"device_id").(string), info["device_id"].(string)),
+					}
+					break
+				}
+			}
+		}
+		pciDevices = append(pciDevices, block)
+	}
+
+	for i, dev := range poolUsb {
+		address := usbAddress(dev.Bus, dev.Device)
+		block := map[string]interface{}{"address": address, "vendor_id": "", "product_id": "", "serial": ""}
+		prefix := fmt.Sprintf("usb_device.%d.", i)
+		if i < d.Get("usb_device.#").(int) && d.Get(prefix+"address").(string) == "" {
+			for j := range hosts {
+				if len(allowed) > 0 && !allowed[hosts[j].Hostid] {
+					continue
+				}
+				for _, usb := range hosts[j].Hardware.UsbDevices {
+					if usbAddress(usb.Busnum, usb.Devnum) != address {
+						continue
+					}
+					block = map[string]interface{}{
+						"address":    "",
+						"vendor_id":  configuredHexID(d.Get(prefix+"vendor_id").(string), fmt.Sprintf("%04x", usb.IDVendor)),
+						"product_id": configuredHexID(d.Get(prefix+"product_id").(string), fmt.Sprintf("%04x", usb.IDProduct)),
+						"serial":     "",
+					}
+					if d.Get(prefix+"serial").(string) != "" {
+						block["serial"] = usb.Serial
+					}
+					break
+				}
+				if block["address"] == "" {
+					break
+				}
+			}
+		}
+		usbDevices = append(usbDevices, block)
+	}
+	return pciDevices, usbDevices, nil
+}
+
+// configuredHexID keeps the configured spelling of a hex id when it matches the id read back
+func configuredHexID(configured, actual string) string {
+	if configured != "" && parseHexID(configured) == parseHexID(actual) {
+		return configured
+	}
+	return actual
+}
+
+// hostDevicesRequested is true when placement is decided by the device blocks
+func hostDevicesRequested(d *schema.ResourceData) bool {
+	return d.Get("gpu_profile").(string) != "" || d.Get("pci_device.#").(int) > 0 || d.Get("usb_device.#").(int) > 0
+}
+
+// poolGpuProfile returns the mdev type from the host devices of a pool
+func poolGpuProfile(devices []*rest.HostDevice) string {
+	for _, dev := range devices {
+		if dev.Type == "mdev" {
+			return dev.MdevType
+		}
+	}
+	return ""
+}
@@ -0,0 +1,156 @@
+package hiveio
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+const importNamePrefix = "name:"
+
+// importName returns the name from an import id in name:<name> format
+func importName(id string) (string, bool) {
+	if !strings.HasPrefix(id, importNamePrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(id, importNamePrefix), true
+}
+
+// splitImportID splits a composite import id and checks it has one part for each field
+func splitImportID(id string, fields ...string) ([]string, error) {
+	parts := strings.SplitN(id, "/", len(fields))
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("unexpected import id %q, expected %s", id, strings.Join(fields, "/"))
+	}
+	for i, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("unexpected import id %q, %s is empty in %s", id, fields[i], strings.Join(fields, "/"))
+		}
+	}
+	return parts, nil
+}
+
+// importPool accepts a pool id or name:<name> for pools of poolType
+func importPool(poolType string) schema.StateContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+		// settings that only live in state start from their defaults so the plan after import is empty
+		d.Set("deletion_protection", false)
+		d.Set("retain_disks_on_destroy", false)
+		name, ok := importName(d.Id())
+		if !ok {
+			return []*schema.ResourceData{d}, nil
+		}
+		client, err := getClient(d, m)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := client.GetPoolByName(name)
+		if err != nil {
+			return nil, fmt.Errorf("pool %s not found: %w", name, err)
+		}
+		if pool.Type != poolType {
+			return nil, fmt.Errorf("pool %s is a %s pool, expected %s", name, pool.Type, poolType)
+		}
+		d.SetId(pool.ID)
+		return []*schema.ResourceData{d}, nil
+	}
+}
+
+// importProfile accepts a profile id or name:<name>
+func importProfile(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	name, ok := importName(d.Id())
+	if !ok {
+		return []*schema.ResourceData{d}, nil
+	}
+	client, err := getClient(d, m)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := client.GetProfileByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s not found: %w", name, err)
+	}
+	d.SetId(profile.ID)
+	return []*schema.ResourceData{d}, nil
+}
+
+// importStoragePool accepts a storage pool id or name:<name>
+func importStoragePool(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	name, ok := importName(d.Id())
+	if !ok {
+		return []*schema.ResourceData{d}, nil
+	}
+	client, err := getClient(d, m)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := client.GetStoragePoolByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("storage pool %s not found: %w", name, err)
+	}
+	d.SetId(storage.ID)
+	return []*schema.ResourceData{d}, nil
+}
+
+// importTemplate accepts a template name with or without the name: prefix
+func importTemplate(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	if name, ok := importName(d.Id()); ok {
+		d.SetId(name)
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+// importDisk accepts <storage name or id>/<filename>
+func importDisk(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	parts, err := splitImportID(d.Id(), "storage", "filename")
+	if err != nil {
+		return nil, err
+	}
+	client, err := getClient(d, m)
+	if err != nil {
+		return nil, err
+	}
+	var storage *rest.StoragePool
+	storage, err = client.GetStoragePoolByName(parts[0])
+	if err != nil {
+		storage, err = client.GetStoragePool(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("storage pool %s not found by name or id", parts[0])
+		}
+	}
+	if _, err := storage.DiskInfo(client, parts[1]); err != nil {
+		return nil, fmt.Errorf("disk %s not found in storage pool %s: %w", parts[1], storage.Name, err)
+	}
+	d.Set("storage_pool", storage.ID)
+	d.Set("filename", parts[1])
+	d.SetId(storage.ID + "-" + parts[1])
+	return []*schema.ResourceData{d}, nil
+}
+
+// importHostNetwork accepts <hostid>/<network name>
+func importHostNetwork(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	parts, err := splitImportID(d.Id(), "hostid", "name")
+	if err != nil {
+		return nil, err
+	}
+	d.Set("hostid", parts[0])
+	d.Set("name", parts[1])
+	return []*schema.ResourceData{d}, nil
+}
+
+// importHostIscsi accepts <hostid>/<portal>/<target>
+func importHostIscsi(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	parts, err := splitImportID(d.Id(), "hostid", "portal", "target")
+	if err != nil {
+		return nil, err
+	}
+	d.Set("hostid", parts[0])
+	d.Set("portal", parts[1])
+	d.Set("discovered_portal", parts[1])
+	d.Set("target", parts[2])
+	d.SetId(parts[1] + "/" + parts[2])
+	return []*schema.ResourceData{d}, nil
+}
@@ -0,0 +1,159 @@
+package hiveio
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+const (
+	labelTagPrefix       = "label:"
+	descriptionTagPrefix = "description:"
+	// labelsAnnotation starts the line that holds the labels of objects without tags
+	labelsAnnotation = "\nterraform-labels: "
+)
+
+var labelKeyRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+func descriptionSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeString,
+		Description: "A description shown in Hive.",
+		Optional:    true,
+	}
+}
+
+func labelsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeMap,
+		Description: "Key value labels, for example the owning team or terraform workspace.",
+		Optional:    true,
+		ValidateDiagFunc: validation.MapKeyMatch(labelKeyRegexp,
+			"label keys may only contain letters, digits, '_', '.' and '-'"),
+		Elem: &schema.Schema{
+			Type: schema.TypeString,
+		},
+	}
+}
+
+func labelsFilterSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeMap,
+		Description: "Only include objects that have all of these labels.",
+		Optional:    true,
+		Elem: &schema.Schema{
+			Type: schema.TypeString,
+		},
+	}
+}
+
+func expandLabels(v interface{}) map[string]string {
+	labels := map[string]string{}
+	for key, value := range v.(map[string]interface{}) {
+		labels[key] = value.(string)
+	}
+	return labels
+}
+
+// labelTags encodes labels as label:<key>=<value> tags
+func labelTags(labels map[string]string) []string {
+	tags := make([]string, 0, len(labels))
+	for _, key := range sortedKeys(labels) {
+		tags = append(tags, fmt.Sprintf("%s%s=%s", labelTagPrefix, key, labels[key]))
+	}
+	return tags
+}
+
+// tagLabels decodes the label tags and returns the other tags unchanged
+func tagLabels(tags []string) (map[string]string, []string) {
+	labels := map[string]string{}
+	var other []string
+	for _, tag := range tags {
+		if label, ok := strings.CutPrefix(tag, labelTagPrefix); ok {
+			if key, value, ok := strings.Cut(label, "="); ok {
+				labels[key] = value
+				continue
+			}
+		}
+		other = append(other, tag)
+	}
+	return labels, other
+}
+
+// keepUnmanagedTags adds the current tags that were set outside terraform to tags. Current tags
+// starting with one of the managed prefixes are replaced by tags.
+func keepUnmanagedTags(current, tags []string, managed ...string) []string {
+	merged := []string{}
+	for _, tag := range current {
+		if !slices.ContainsFunc(managed, func(prefix string) bool { return strings.HasPrefix(tag, prefix) }) {
+			merged = append(merged, tag)
+		}
+	}
+	return append(merged, tags...)
+}
+
+// profileTags stores the description and labels of a profile in its tags
+func profileTags(description string, labels map[string]string) []string {
+	tags := labelTags(labels)
+	if description != "" {
+		tags = append(tags, descriptionTagPrefix+description)
+	}
+	return tags
+}
+
+// profileTagValues reads the description and labels from the tags of a profile
+func profileTagValues(tags []string) (string, map[string]string) {
+	labels, other := tagLabels(tags)
+	for _, tag := range other {
+		if description, ok := strings.CutPrefix(tag, descriptionTagPrefix); ok {
+			return description, labels
+		}
+	}
+	return "", labels
+}
+
+// annotateDescription appends the labels to a description for objects that have no tags
+func annotateDescription(description string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return description
+	}
+	// json.Marshal sorts map keys so the annotation is stable
+	encoded, _ := json.Marshal(labels)
+	return description + labelsAnnotation + string(encoded)
+}
+
+// parseAnnotatedDescription splits the labels from a description written by annotateDescription
+func parseAnnotatedDescription(annotated string) (string, map[string]string) {
+	labels := map[string]string{}
+	i := strings.LastIndex(annotated, labelsAnnotation)
+	if i == -1 {
+		return annotated, labels
+	}
+	if err := json.Unmarshal([]byte(annotated[i+len(labelsAnnotation):]), &labels); err != nil {
+		return annotated, map[string]string{}
+	}
+	return annotated[:i], labels
+}
+
+// labelsMatch reports whether labels has every label in filter
+func labelsMatch(labels map[string]string, filter map[string]string) bool {
+	for key, value := range filter {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// flattenLabels returns nil for no labels so an unset labels attribute has no diff
+func flattenLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
@@ -0,0 +1,114 @@
+package hiveio
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+// migrationDestination returns the available host from allowedHosts with the most free memory
+// that has enough threads and free memory for guest. The free memory of a host is its physical
+// memory minus the memory of the guests running on it.
+func migrationDestination(client *rest.Client, guest *rest.Guest, allowedHosts []string) (string, error) {
+	hosts, err := client.ListHosts("")
+	if err != nil {
+		return "", fmt.Errorf("failed to list hosts: %w", err)
+	}
+	guests, err := client.ListGuests("")
+	if err != nil {
+		return "", fmt.Errorf("failed to list guests: %w", err)
+	}
+	used := map[string]int{}
+	for _, g := range guests {
+		if g.Hostid != "" {
+			used[g.Hostid] += g.Memory
+		}
+	}
+
+	destination, destinationFree := "", 0
+	var skipped []string
+	for _, host := range hosts {
+		if !slices.Contains(allowedHosts, host.Hostid) {
+			continue
+		}
+		threads := host.Hardware.PhysicalCPUs * host.Hardware.PhysicalCoresPerCPU
+		if host.Hardware.HyperThreadingEnabled {
+			threads *= 2
+		}
+		free := host.Hardware.TotalPhysicalMemory/1024/1024 - used[host.Hostid]
+		switch {
+		case host.State != "available":
+			skipped = append(skipped, fmt.Sprintf("%s is %s", host.Hostid, host.State))
+		case threads < guest.Cpus:
+			skipped = append(skipped, fmt.Sprintf("%s has %d threads, %d are needed", host.Hostid, threads, guest.Cpus))
+		case free < guest.Memory:
+			skipped = append(skipped, fmt.Sprintf("%s has %d MB free memory, %d MB are needed", host.Hostid, free, guest.Memory))
+		case destination == "" || free > destinationFree:
+			destination, destinationFree = host.Hostid, free
+		}
+	}
+	if destination == "" {
+		if len(skipped) == 0 {
+			return "", fmt.Errorf("none of the allowed hosts exist: %s", strings.Join(allowedHosts, ", "))
+		}
+		return "", fmt.Errorf("none of the allowed hosts can run %s: %s", guest.Name, strings.Join(skipped, "; "))
+	}
+	return destination, nil
+}
+
+// migrateToAllowedHost live migrates a running guest when its host is not in allowedHosts. Guests
+// that are off are not migrated, Hive starts them on an allowed host. Guests that are starting or
+// stopping can not be migrated and return an error.
+func migrateToAllowedHost(ctx context.Context, client *rest.Client, guest *rest.Guest, allowedHosts []string, timeout time.Duration) error {
+	if guest.Hostid == "" || len(allowedHosts) == 0 || slices.Contains(allowedHosts, guest.Hostid) {
+		return nil
+	}
+	if guest.GuestState == "off" {
+		return nil
+	}
+	if !rest.IsGuestReady(*guest) {
+		return fmt.Errorf("%s is %s on host %s which is not allowed, apply again when it is running", guest.Name, guest.GuestState, guest.Hostid)
+	}
+	destination, err := migrationDestination(client, guest, allowedHosts)
+	if err != nil {
+		return fmt.Errorf("failed to migrate %s: %w", guest.Name, err)
+	}
+	err = guest.Migrate(client, destination)
+	if err != nil {
+		return fmt.Errorf("failed to migrate %s to %s: %w", guest.Name, destination, err)
+	}
+
+	// Migrate returns no task, a migration failed when the guest reports a new error or stops
+	// migrating on another host
+	var failure error
+	started := false
+	err = guest.WaitForGuestChange(ctx, client, remainingTimeout(ctx, timeout), func(g rest.Guest) bool {
+		if g.Error != nil && (guest.Error == nil || *g.Error != *guest.Error) {
+			failure = fmt.Errorf("%s: %s", g.Error.Code, g.Error.Message)
+			return true
+		}
+		if g.MigrationProcessing {
+			started = true
+			return false
+		}
+		if g.Hostid == destination {
+			return true
+		}
+		if started {
+			failure = fmt.Errorf("the guest stayed on host %s", g.Hostid)
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for %s to migrate to %s: %w", guest.Name, destination, err)
+	}
+	if failure != nil {
+		return fmt.Errorf("failed to migrate %s to %s: %w", guest.Name, destination, failure)
+	}
+	return nil
+}
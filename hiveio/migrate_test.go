@@ -0,0 +1,184 @@
+package hiveio
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func testHost(hostid, state string, threads, memory int) rest.Host {
+	host := rest.Host{Hostid: hostid, State: state}
+	host.Hardware.PhysicalCPUs = 1
+	host.Hardware.PhysicalCoresPerCPU = threads
+	host.Hardware.TotalPhysicalMemory = memory * 1024 * 1024
+	return host
+}
+
+func TestMigrationDestination(t *testing.T) {
+	guest := &rest.Guest{Name: "KUBUNTU", Cpus: 4, Memory: 4096, Hostid: "host1"}
+	tests := []struct {
+		name         string
+		hosts        []rest.Host
+		allowedHosts []string
+		want         string
+		wantErr      string
+	}{
+		{
+			name:         "most free memory",
+			hosts:        []rest.Host{testHost("host2", "available", 8, 16384), testHost("host3", "available", 8, 32768)},
+			allowedHosts: []string{"host2", "host3"},
+			want:         "host3",
+		},
+		{
+			name:         "memory used by guests",
+			hosts:        []rest.Host{testHost("host2", "available", 8, 16384), testHost("host3", "available", 8, 20480)},
+			allowedHosts: []string{"host2", "host3"},
+			want:         "host2",
+		},
+		{
+			name:         "not allowed",
+			hosts:        []rest.Host{testHost("host2", "available", 8, 16384), testHost("host4", "available", 8, 65536)},
+			allowedHosts: []string{"host2"},
+			want:         "host2",
+		},
+		{
+			name:         "too few threads",
+			hosts:        []rest.Host{testHost("host2", "available", 2, 16384)},
+			allowedHosts: []string{"host2"},
+			wantErr:      "host2 has 2 threads, 4 are needed",
+		},
+		{
+			name:         "too little memory",
+			hosts:        []rest.Host{testHost("host3", "available", 8, 15360)},
+			allowedHosts: []string{"host3"},
+			wantErr:      "host3 has 3072 MB free memory, 4096 MB are needed",
+		},
+		{
+			name:         "maintenance",
+			hosts:        []rest.Host{testHost("host2", "maintenance", 8, 16384)},
+			allowedHosts: []string{"host2"},
+			wantErr:      "host2 is maintenance",
+		},
+		{
+			name:         "missing",
+			allowedHosts: []string{"host5"},
+			wantErr:      "none of the allowed hosts exist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hive, client := newFakeHive(t)
+			hive.hosts = tt.hosts
+			hive.guests = []rest.Guest{*guest, {Name: "OTHER", Hostid: "host3", Memory: 12288}}
+			got, err := migrationDestination(client, guest, tt.allowedHosts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMigrateToAllowedHost(t *testing.T) {
+	tests := []struct {
+		name       string
+		state      string
+		hostid     string
+		result     func(*rest.Guest)
+		wantHost   string
+		wantErr    string
+		wantNoCall bool
+	}{
+		{
+			name:     "migrated",
+			state:    "ready",
+			hostid:   "host1",
+			result:   func(g *rest.Guest) { g.Hostid = "host2" },
+			wantHost: "host2",
+		},
+		{
+			name:    "guest error",
+			state:   "ready",
+			hostid:  "host1",
+			result:  func(g *rest.Guest) { g.Error = &rest.GuestError{Code: "migration", Message: "destination refused"} },
+			wantErr: "destination refused",
+		},
+		{
+			name:    "stayed on host",
+			state:   "ready",
+			hostid:  "host1",
+			result:  func(g *rest.Guest) {},
+			wantErr: "the guest stayed on host host1",
+		},
+		{
+			name:       "already allowed",
+			state:      "ready",
+			hostid:     "host2",
+			wantHost:   "host2",
+			wantNoCall: true,
+		},
+		{
+			name:       "off",
+			state:      "off",
+			hostid:     "host1",
+			wantHost:   "host1",
+			wantNoCall: true,
+		},
+		{
+			name:       "booting",
+			state:      "booting",
+			hostid:     "host1",
+			wantErr:    "KUBUNTU is booting on host host1",
+			wantNoCall: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hive, client := newFakeHive(t)
+			hive.hosts = []rest.Host{testHost("host1", "available", 8, 16384), testHost("host2", "available", 8, 16384)}
+			guest := testVMGuest()
+			guest.GuestState, guest.Hostid, guest.Cpus, guest.Memory = tt.state, tt.hostid, 2, 4096
+			hive.guests = []rest.Guest{guest}
+			hive.mux.HandleFunc("POST /api/guest/{name}/migrate", func(w http.ResponseWriter, r *http.Request) {
+				hive.updateGuest(r.PathValue("name"), func(g *rest.Guest) { g.MigrationProcessing = true })
+				go func() {
+					time.Sleep(50 * time.Millisecond)
+					hive.updateGuest(guest.Name, func(g *rest.Guest) {
+						g.MigrationProcessing = false
+						tt.result(g)
+					})
+				}()
+			})
+
+			err := migrateToAllowedHost(context.Background(), client, &guest, []string{"host2"}, time.Minute)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			hive.mu.Lock()
+			defer hive.mu.Unlock()
+			if called := slices.Contains(hive.requests, "POST /api/guest/KUBUNTU/migrate"); called == tt.wantNoCall {
+				t.Fatalf("expected migrate to be called: %t", !tt.wantNoCall)
+			}
+			if tt.wantHost != "" && hive.guests[0].Hostid != tt.wantHost {
+				t.Fatalf("expected the guest on %s, got %s", tt.wantHost, hive.guests[0].Hostid)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package hiveio
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+// poolBuildProgress is the number of guests of a pool that are ready
+type poolBuildProgress struct {
+	state  string
+	guests int
+	ready  int
+	target int
+}
+
+func (p poolBuildProgress) String() string {
+	return fmt.Sprintf("%d of %d guests ready, %d built, pool state %s", p.ready, p.target, p.guests, p.state)
+}
+
+// getPoolBuildProgress counts the ready guests of a pool and fails on guests that report an error
+func getPoolBuildProgress(client *rest.Client, poolID string) (*poolBuildProgress, error) {
+	pool, err := client.GetPool(poolID)
+	if err != nil {
+		return nil, err
+	}
+	guests, err := client.ListGuests("poolId=" + url.QueryEscape(poolID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list guests for pool %s: %w", pool.Name, err)
+	}
+	progress := poolBuildProgress{state: pool.State}
+	if len(pool.Density) > 0 {
+		progress.target = pool.Density[0]
+	}
+	for _, guest := range guests {
+		if guest.PoolID != poolID {
+			continue
+		}
+		if guest.Error != nil && guest.Error.Message != "" {
+			return nil, fmt.Errorf("guest %s in pool %s failed: %s", guest.Name, pool.Name, guest.Error.Message)
+		}
+		progress.guests++
+		if rest.IsGuestReady(guest) {
+			progress.ready++
+		}
+	}
+	return &progress, nil
+}
+
+// validateBuildWait rejects waiting for more guests than the pool builds with its current density
+func validateBuildWait(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	want := d.Get("wait_for_build_guests").(int)
+	if want == 0 || !d.NewValueKnown("active_density") {
+		return nil
+	}
+	if density := intList(d.Get("active_density").([]interface{})); len(density) > 0 && want > density[0] {
+		return fmt.Errorf("wait_for_build_guests is %d but the pool only builds %d guests with density %d-%d", want, density[0], density[0], density[len(density)-1])
+	}
+	return nil
+}
+
+// waitForPoolBuild waits for the pool to reach the tracking state, or for wait_for_build_guests
+// guests to be ready when it is set
+func waitForPoolBuild(ctx context.Context, client *rest.Client, d *schema.ResourceData, poolID string, timeout time.Duration) error {
+	wantReady := d.Get("wait_for_build_guests").(int)
+	var last *poolBuildProgress
+	err := retry.RetryContext(ctx, timeout, func() *retry.RetryError {
+		progress, err := getPoolBuildProgress(client, poolID)
+		if err != nil {
+			return retry.NonRetryableError(err)
+		}
+		last = progress
+		tflog.Info(ctx, "building guest pool", map[string]interface{}{
+			"pool_id": poolID,
+			"ready":   progress.ready,
+			"built":   progress.guests,
+			"density": progress.target,
+			"state":   progress.state,
+		})
+		if wantReady > 0 && progress.ready >= wantReady {
+			return nil
+		}
+		if wantReady == 0 && progress.state == "tracking" {
+			return nil
+		}
+		time.Sleep(10 * time.Second)
+		return retry.RetryableError(fmt.Errorf("building pool %s: %s", poolID, progress))
+	})
+	if err != nil && last != nil {
+		return fmt.Errorf("waiting for pool %s to build (%s): %w", poolID, last, err)
+	}
+	return err
+}
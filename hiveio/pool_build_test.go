@@ -0,0 +1,65 @@
+package hiveio
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValidateBuildWait(t *testing.T) {
+	_, client := newFakeHive(t)
+	// two windows that cover the whole day so the schedule applies whenever the test runs
+	allDayWindows := `[
+		{"days": "*", "start": "00:00", "end": "12:00", "density": [4, 8]},
+		{"days": "*", "start": "12:00", "end": "00:00", "density": [4, 8]}
+	]`
+	tests := []struct {
+		name     string
+		density  string
+		schedule string
+		guests   int
+		wantErr  string
+	}{
+		{name: "not waiting for guests", density: "[2, 5]"},
+		{name: "below the minimum", density: "[2, 5]", guests: 1},
+		{name: "at the minimum", density: "[2, 5]", guests: 2},
+		{name: "above the minimum", density: "[2, 5]", guests: 3, wantErr: "wait_for_build_guests is 3 but the pool only builds 2 guests with density 2-5"},
+		{name: "empty pool", density: "[0, 5]", guests: 1, wantErr: "only builds 0 guests"},
+		{
+			name:     "active schedule window",
+			density:  "[1, 2]",
+			schedule: allDayWindows,
+			guests:   4,
+		},
+		{
+			name:     "above the active schedule window",
+			density:  "[1, 2]",
+			schedule: allDayWindows,
+			guests:   5,
+			wantErr:  "density 4-8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := `{"name": "desktops", "profile": "default", "template": "ubuntu", "seed": "DESK", "wait_for_build": true`
+			config += `, "density": ` + tt.density
+			if tt.schedule != "" {
+				config += `, "schedule": ` + tt.schedule
+			}
+			if tt.guests > 0 {
+				config += `, "wait_for_build_guests": ` + strconv.Itoa(tt.guests)
+			}
+			config += "}"
+			_, err := planResource(t, resourceGuestPool(), nil, config, client)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("plan failed: %s", err)
+			}
+		})
+	}
+}
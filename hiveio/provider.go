@@ -127,28 +127,39 @@ func Provider() *schema.Provider {
 
 		Schema: providerSchema,
 		DataSourcesMap: map[string]*schema.Resource{
-			"hiveio_profile":      dataSourceProfile(),
-			"hiveio_storage_pool": dataSourceStoragePool(),
-			"hiveio_host":         dataSourceHost(),
-			"hiveio_host_network": dataSourceHostNetwork(),
-			"hiveio_version":      dataSourceVersion(),
+			"hiveio_profile":          dataSourceProfile(),
+			"hiveio_storage_pool":     dataSourceStoragePool(),
+			"hiveio_host":             dataSourceHost(),
+			"hiveio_host_network":     dataSourceHostNetwork(),
+			"hiveio_version":          dataSourceVersion(),
+			"hiveio_backups":          dataSourceBackups(),
+			"hiveio_host_devices":     dataSourceHostDevices(),
+			"hiveio_pools":            dataSourcePools(),
+			"hiveio_pool_assignments": dataSourcePoolAssignments(),
+			"hiveio_pool_guests":      dataSourcePoolGuests(),
 		},
 		ResourcesMap: map[string]*schema.Resource{
-			"hiveio_host":            resourceHost(),
-			"hiveio_realm":           resourceRealm(),
-			"hiveio_profile":         resourceProfile(),
-			"hiveio_storage_pool":    resourceStoragePool(),
-			"hiveio_disk":            resourceDisk(),
-			"hiveio_template":        resourceTemplate(),
-			"hiveio_guest_pool":      resourceGuestPool(),
-			"hiveio_virtual_machine": resourceVM(),
-			"hiveio_license":         resourceLicense(),
-			"hiveio_external_guest":  resourceExternalGuest(),
-			"hiveio_user":            resourceUser(),
-			"hiveio_shared_storage":  resourceSharedStorage(),
-			"hiveio_host_network":    resourceHostNetwork(),
-			"hiveio_host_iscsi":      resourceHostIscsi(),
-			"hiveio_gateway_host":    resourceGatewayHost(),
+			"hiveio_host":             resourceHost(),
+			"hiveio_realm":            resourceRealm(),
+			"hiveio_profile":          resourceProfile(),
+			"hiveio_storage_pool":     resourceStoragePool(),
+			"hiveio_disk":             resourceDisk(),
+			"hiveio_template":         resourceTemplate(),
+			"hiveio_guest_pool":       resourceGuestPool(),
+			"hiveio_virtual_machine":  resourceVM(),
+			"hiveio_license":          resourceLicense(),
+			"hiveio_external_guest":   resourceExternalGuest(),
+			"hiveio_user":             resourceUser(),
+			"hiveio_shared_storage":   resourceSharedStorage(),
+			"hiveio_host_network":     resourceHostNetwork(),
+			"hiveio_host_iscsi":       resourceHostIscsi(),
+			"hiveio_gateway_host":     resourceGatewayHost(),
+			"hiveio_vm_snapshot":      resourceVMSnapshot(),
+			"hiveio_backup":           resourceBackup(),
+			"hiveio_backup_restore":   resourceBackupRestore(),
+			"hiveio_template_from_vm": resourceTemplateFromVM(),
+			"hiveio_placement_group":  resourcePlacementGroup(),
+			"hiveio_pool_assignment":  resourcePoolAssignment(),
 		},
 
 		ConfigureFunc: providerConfigure,
@@ -0,0 +1,139 @@
+package hiveio
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func resourceBackup() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Takes an on-demand backup of a guest and waits for it to finish. Changing triggers takes a new backup. Destroying the resource keeps the backup, deleting backups is not available through the Hive api.",
+		CreateContext: resourceBackupCreate,
+		ReadContext:   resourceBackupRead,
+		DeleteContext: resourceBackupDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(60 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"guest_name": {
+				Type:        schema.TypeString,
+				Description: "The name of the guest to back up.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"storage_id": {
+				Type:        schema.TypeString,
+				Description: "The storage pool to store the backup in. Defaults to the backup target of the pool.",
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+			},
+			"triggers": {
+				Type:        schema.TypeMap,
+				Description: "Arbitrary values that take a new backup when they change.",
+				Optional:    true,
+				ForceNew:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"backup": {
+				Type:        schema.TypeString,
+				Description: "The name of the backup, which can be restored with hiveio_backup_restore.",
+				Computed:    true,
+			},
+			"provider_override": &providerOverride,
+		},
+	}
+}
+
+func resourceBackupCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	guest, err := client.GetGuest(d.Get("guest_name").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	storageID := d.Get("storage_id").(string)
+	if storageID == "" {
+		pool, err := client.GetPool(guest.PoolID)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		storageID, err = backupStorageID(pool)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	before, err := guest.ListBackups(client, storageID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	task, err := guest.StartBackup(client, storageID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if task == nil {
+		return diag.Errorf("Failed to back up %s: Task was not returned", guest.Name)
+	}
+	ctx, cancel := context.WithTimeout(ctx, d.Timeout(schema.TimeoutCreate))
+	defer cancel()
+	task, err = task.WaitForTaskWithContext(ctx, client, false)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if task.State == "failed" {
+		return diag.Errorf("Failed to back up %s: %s", guest.Name, task.Message)
+	}
+	after, err := guest.ListBackups(client, storageID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	var backup string
+	for _, name := range after {
+		if !slices.Contains(before, name) {
+			backup = name
+		}
+	}
+	if backup == "" {
+		return diag.Errorf("Failed to back up %s: the backup is not listed in storage pool %s", guest.Name, storageID)
+	}
+	d.Set("storage_id", storageID)
+	d.Set("backup", backup)
+	d.SetId(guest.Name + "-" + backup)
+	return resourceBackupRead(ctx, d, m)
+}
+
+func resourceBackupRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	guest, err := client.GetGuest(d.Get("guest_name").(string))
+	if err != nil && strings.Contains(err.Error(), "\"error\": 404") {
+		d.SetId("")
+		return diag.Diagnostics{}
+	} else if err != nil {
+		return diag.FromErr(err)
+	}
+	backups, err := guest.ListBackups(client, d.Get("storage_id").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if !slices.Contains(backups, d.Get("backup").(string)) {
+		d.SetId("")
+	}
+	return diag.Diagnostics{}
+}
+
+func resourceBackupDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return diag.Diagnostics{}
+}
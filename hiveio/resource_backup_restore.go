@@ -0,0 +1,159 @@
+package hiveio
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func resourceBackupRestore() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Restores a guest from a backup. The restore runs when the resource is created and destroying the resource does not change the guest. The backup is restored in place, or as a new virtual machine when vm_id is set to a clone of the virtual machine.",
+		CreateContext: resourceBackupRestoreCreate,
+		ReadContext:   resourceBackupRestoreRead,
+		DeleteContext: resourceBackupRestoreDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(30 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"guest_name": {
+				Type:        schema.TypeString,
+				Description: "The name of the guest to restore.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"backup": {
+				Type:        schema.TypeString,
+				Description: "The backup to restore from the hiveio_backups data source.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"vm_id": {
+				Type:        schema.TypeString,
+				Description: "Restore the backup as a new virtual machine: the id of a hiveio_virtual_machine created with clone_from vm_id set to the virtual machine of guest_name. Its disks are replaced by the backup and the guest of guest_name is not changed.",
+				Optional:    true,
+				ForceNew:    true,
+			},
+			"restored_guest_name": {
+				Type:        schema.TypeString,
+				Description: "The name of the guest the backup was restored into, guest_name or the guest of vm_id.",
+				Computed:    true,
+			},
+			"storage_id": {
+				Type:        schema.TypeString,
+				Description: "The storage pool the backup is stored in. Defaults to the backup target of the pool.",
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+			},
+			"provider_override": &providerOverride,
+		},
+	}
+}
+
+func resourceBackupRestoreCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	source, err := client.GetGuest(d.Get("guest_name").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	pool, err := client.GetPool(source.PoolID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	storageID := d.Get("storage_id").(string)
+	if storageID == "" {
+		storageID, err = backupStorageID(pool)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	guest := source
+	if vmID, ok := d.GetOk("vm_id"); ok {
+		guest, err = restoreTarget(client, pool, vmID.(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	backup := d.Get("backup").(string)
+	task, err := guest.Restore(client, storageID, backup)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if task == nil {
+		return diag.Errorf("Failed to restore %s: Task was not returned", guest.Name)
+	}
+	ctx, cancel := context.WithTimeout(ctx, d.Timeout(schema.TimeoutCreate))
+	defer cancel()
+	task, err = task.WaitForTaskWithContext(ctx, client, false)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if task.State == "failed" {
+		return diag.Errorf("Failed to restore %s: %s", guest.Name, task.Message)
+	}
+	d.Set("storage_id", storageID)
+	d.Set("restored_guest_name", guest.Name)
+	d.SetId(guest.Name + "-" + backup)
+	return resourceBackupRestoreRead(ctx, d, m)
+}
+
+// restoreTarget returns the guest of the vm vmID to restore a backup of a guest of pool into. The vm
+// must have the same disks as pool, which a vm created with clone_from vm_id has.
+func restoreTarget(client *rest.Client, pool *rest.Pool, vmID string) (*rest.Guest, error) {
+	target, err := client.GetPool(vmID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vm %s: %w", vmID, err)
+	}
+	if target.Type != "standalone" {
+		return nil, fmt.Errorf("%s is not a virtual machine", target.Name)
+	}
+	if target.ID == pool.ID {
+		return nil, fmt.Errorf("vm_id is the vm of the guest, remove it to restore in place")
+	}
+	countDisks := func(p *rest.Pool) int {
+		n := 0
+		for _, disk := range p.GuestProfile.Disks {
+			if !isCdrom(disk.Type) {
+				n++
+			}
+		}
+		return n
+	}
+	if want, got := countDisks(pool), countDisks(target); want != got {
+		return nil, fmt.Errorf("%s has %d disks and %s has %d, restore into a vm created with clone_from vm_id = %q", pool.Name, want, target.Name, got, pool.ID)
+	}
+	return getPoolGuest(client, target.ID)
+}
+
+func resourceBackupRestoreRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	name := d.Get("restored_guest_name").(string)
+	if name == "" {
+		name = d.Get("guest_name").(string)
+	}
+	_, err = client.GetGuest(name)
+	if err != nil && strings.Contains(err.Error(), "\"error\": 404") {
+		d.SetId("")
+		return diag.Diagnostics{}
+	} else if err != nil {
+		return diag.FromErr(err)
+	}
+	return diag.Diagnostics{}
+}
+
+func resourceBackupRestoreDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return diag.Diagnostics{}
+}
@@ -0,0 +1,111 @@
+package hiveio
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+const testCloneID = "8d2e4a51-6b0c-4f7e-b3a9-1e5d7c9f0a24"
+
+func TestBackupRestore(t *testing.T) {
+	tests := []struct {
+		name      string
+		vmID      string
+		cloneDisk bool
+		want      string
+		wantErr   string
+	}{
+		{
+			name: "in place",
+			want: "KUBUNTU",
+		},
+		{
+			name:      "new vm",
+			vmID:      testCloneID,
+			cloneDisk: true,
+			want:      "KUBUNTU-RESTORED",
+		},
+		{
+			name:    "different disks",
+			vmID:    testCloneID,
+			wantErr: "kubuntu has 1 disks and kubuntu-restored has 0",
+		},
+		{
+			name:    "same vm",
+			vmID:    testVMID,
+			wantErr: "remove it to restore in place",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hive, client := newFakeHive(t)
+			source := testVMPool()
+			source.Backup = &rest.PoolBackup{TargetStorageID: "backups"}
+			clone := testVMPool()
+			clone.ID, clone.Name = testCloneID, "kubuntu-restored"
+			clone.GuestProfile = &rest.PoolGuestProfile{}
+			if tt.cloneDisk {
+				clone.GuestProfile.Disks = []*rest.PoolDisk{{Type: "Disk", StorageID: "disks", Filename: "kubuntu-restored-disk0.qcow2"}}
+			}
+			hive.pools[testVMID], hive.pools[testCloneID] = source, clone
+			cloneGuest := testVMGuest()
+			cloneGuest.Name, cloneGuest.PoolID = "KUBUNTU-RESTORED", testCloneID
+			hive.guests = []rest.Guest{testVMGuest(), cloneGuest}
+
+			var restored []string
+			hive.mux.HandleFunc("POST /api/guest/{name}/restore", func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					StorageID string `json:"storageId"`
+					Backup    string `json:"backup"`
+				}
+				if !hive.readJSON(w, r, &body) {
+					return
+				}
+				if body.StorageID != "backups" || body.Backup != "kubuntu-20260101" {
+					t.Errorf("unexpected restore of %s from %s", body.Backup, body.StorageID)
+				}
+				task := rest.Task{ID: uuid.NewString(), State: "completed"}
+				hive.mu.Lock()
+				restored = append(restored, r.PathValue("name"))
+				hive.tasks[task.ID] = task
+				hive.mu.Unlock()
+				hive.writeJSON(w, map[string]string{"taskId": task.ID})
+			})
+
+			r := resourceBackupRestore()
+			d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+				"guest_name": "KUBUNTU",
+				"backup":     "kubuntu-20260101",
+				"vm_id":      tt.vmID,
+			})
+			diags := r.CreateContext(context.Background(), d, client)
+			if tt.wantErr != "" {
+				if !diags.HasError() || !strings.Contains(diags[0].Summary, tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, diags)
+				}
+				if len(restored) > 0 {
+					t.Fatalf("expected no restore, restored %v", restored)
+				}
+				return
+			}
+			if diags.HasError() {
+				t.Fatalf("create failed: %v", diags)
+			}
+			if len(restored) != 1 || restored[0] != tt.want {
+				t.Fatalf("expected a restore into %s, restored %v", tt.want, restored)
+			}
+			if got := d.Get("restored_guest_name").(string); got != tt.want {
+				t.Errorf("expected restored_guest_name %s, got %s", tt.want, got)
+			}
+			if got := d.Get("storage_id").(string); got != "backups" {
+				t.Errorf("expected the backup target of the pool, got %s", got)
+			}
+		})
+	}
+}
@@ -16,7 +16,7 @@ func resourceDisk() *schema.Resource {
 		ReadContext:   resourceDiskRead,
 		DeleteContext: resourceDiskDelete,
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importDisk,
 		},
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(20 * time.Minute),
@@ -7,8 +7,10 @@ import (
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/hive-io/hive-go-client/rest"
 )
 
@@ -18,11 +20,14 @@ func resourceGuestPool() *schema.Resource {
 		ReadContext:   resourceGuestPoolRead,
 		UpdateContext: resourceGuestPoolUpdate,
 		DeleteContext: resourceGuestPoolDelete,
+		CustomizeDiff: customdiff.All(validateGuestTemplate, validateGuestResources, validateHostDevices, validatePoolHostDevices, validateRollout, diffScheduledDensity, validateBuildWait, validateGuestNaming),
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importPool("vdi"),
 		},
 		Timeouts: &schema.ResourceTimeout{
-			Delete: schema.DefaultTimeout(5 * time.Minute),
+			Create: schema.DefaultTimeout(60 * time.Minute),
+			Update: schema.DefaultTimeout(60 * time.Minute),
+			Delete: schema.DefaultTimeout(60 * time.Minute),
 		},
 
 		Schema: map[string]*schema.Schema{
@@ -31,6 +36,8 @@ func resourceGuestPool() *schema.Resource {
 				Required: true,
 				ForceNew: true,
 			},
+			"description": descriptionSchema(),
+			"labels":      labelsSchema(),
 			"density": {
 				Type:     schema.TypeList,
 				Required: true,
@@ -48,11 +55,18 @@ func resourceGuestPool() *schema.Resource {
 				Type:     schema.TypeInt,
 				Optional: true,
 			},
+			"cpu_min":    rangeBoundSchema("The minimum number of vcpus. Defaults to cpu."),
+			"cpu_max":    rangeBoundSchema("The maximum number of vcpus. Defaults to cpu."),
+			"memory_min": rangeBoundSchema("The minimum memory in MB when memory ballooning is used. Defaults to memory."),
+			"memory_max": rangeBoundSchema("The maximum memory in MB when memory ballooning is used. Defaults to memory."),
 			"gpu": {
 				Type:     schema.TypeBool,
 				Default:  false,
 				Optional: true,
 			},
+			"gpu_profile": gpuProfileSchema(),
+			"pci_device":  pciDeviceSchema(),
+			"usb_device":  usbDeviceSchema(),
 			"persistent": {
 				Type:     schema.TypeBool,
 				Default:  false,
@@ -68,9 +82,40 @@ func resourceGuestPool() *schema.Resource {
 				Required: true,
 			},
 			"seed": {
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+				Type:        schema.TypeString,
+				Description: "The prefix of the guest names. Hive names each guest with the seed in upper case followed by a counter of at least three digits. With a windows template the names are checked against NetBIOS limits at plan time.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"interface": {
+				Type:        schema.TypeList,
+				Description: "Network interfaces for the guests instead of the interfaces of the template. Removing all interface blocks sets the interfaces of the template again.",
+				Optional:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"network": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"vlan": {
+							Type:     schema.TypeInt,
+							Optional: true,
+						},
+						"emulation": {
+							Type:     schema.TypeString,
+							Default:  "virtio",
+							Optional: true,
+						},
+					},
+				},
+			},
+			"guest_names": {
+				Type:        schema.TypeList,
+				Description: "The names of the guests in the pool.",
+				Computed:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
 			},
 			"storage_type": {
 				Type:     schema.TypeString,
@@ -115,6 +160,12 @@ func resourceGuestPool() *schema.Resource {
 				Default:  "",
 				Optional: true,
 			},
+			"cloudinit_networkconfig": {
+				Type:     schema.TypeString,
+				Default:  "",
+				Optional: true,
+			},
+			"cloudinit_guest_variables": guestVariablesSchema(),
 			"allowed_hosts": {
 				Type:     schema.TypeList,
 				Optional: true,
@@ -123,9 +174,27 @@ func resourceGuestPool() *schema.Resource {
 				},
 			},
 			"wait_for_build": {
-				Type:     schema.TypeBool,
-				Default:  false,
-				Optional: true,
+				Type:        schema.TypeBool,
+				Description: "Wait for the guests of the pool to be built after it is created or updated. Progress is logged and a guest that fails to build fails the apply.",
+				Default:     false,
+				Optional:    true,
+			},
+			"rollout":  rolloutSchema(),
+			"schedule": scheduleSchema(),
+			"active_density": {
+				Type:        schema.TypeList,
+				Description: "The density the pool runs with now, from the schedule window that covers the current time or density.",
+				Computed:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeInt,
+				},
+			},
+			"wait_for_build_guests": {
+				Type:         schema.TypeInt,
+				Description:  "With wait_for_build, wait until this many guests are ready instead of for the whole pool to be built. Cannot be more than the minimum density.",
+				Default:      0,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
 			},
 			"broker_default_connection": {
 				Type:     schema.TypeString,
@@ -188,21 +257,34 @@ func resourceGuestPool() *schema.Resource {
 					},
 				},
 			},
+			"deletion_protection": deletionProtectionSchema(),
+			"retain_disks_on_destroy": {
+				Type:        schema.TypeBool,
+				Description: "Shut down the persistent guests and copy their disks before the pool is destroyed so user data is kept. The copies are listed in retained_disks. The guests are copied one after another within the delete timeout, which defaults to 60 minutes.",
+				Default:     false,
+				Optional:    true,
+			},
+			"retained_disks":    retainedDisksSchema(),
 			"provider_override": &providerOverride,
 		},
 	}
 }
 
-func poolFromResource(d *schema.ResourceData) *rest.Pool {
+func poolFromResource(d *schema.ResourceData) (*rest.Pool, error) {
 	pool := rest.Pool{
 		Name:        d.Get("name").(string),
+		Description: d.Get("description").(string),
+		Tags:        labelTags(expandLabels(d.Get("labels"))),
 		ProfileID:   d.Get("profile").(string),
 		Seed:        d.Get("seed").(string),
 		InjectAgent: true,
 		StorageID:   d.Get("storage_id").(string),
 		StorageType: d.Get("storage_type").(string),
 		Type:        "vdi",
-		Density:     []int{d.Get("density.0").(int), d.Get("density.1").(int)},
+		Density:     intList(d.Get("active_density").([]interface{})),
+	}
+	if len(pool.Density) != 2 {
+		pool.Density = scheduledDensity(d, time.Now())
 	}
 
 	guestProfile := rest.PoolGuestProfile{
@@ -211,16 +293,28 @@ func poolFromResource(d *schema.ResourceData) *rest.Pool {
 		Gpu:          d.Get("gpu").(bool),
 	}
 
-	if cpu, ok := d.GetOk("cpu"); ok {
-		guestProfile.CPU = []int{cpu.(int), cpu.(int)}
-	}
-	if mem, ok := d.GetOk("memory"); ok {
-		guestProfile.Mem = []int{mem.(int), mem.(int)}
+	guestProfile.CPU = guestProfileRange(d, "cpu", "cpu_min", "cpu_max")
+	for i := 0; i < d.Get("interface.#").(int); i++ {
+		prefix := fmt.Sprintf("interface.%d.", i)
+		guestProfile.Interfaces = append(guestProfile.Interfaces, &rest.PoolInterface{
+			Emulation: d.Get(prefix + "emulation").(string),
+			Network:   d.Get(prefix + "network").(string),
+			Vlan:      d.Get(prefix + "vlan").(int),
+		})
 	}
+	guestProfile.Mem = guestProfileRange(d, "memory", "memory_min", "memory_max")
 	if cloudInitEnabled := d.Get("cloudinit_enabled").(bool); cloudInitEnabled {
 		cloudInit := rest.PoolCloudInit{
-			Enabled:  cloudInitEnabled,
-			UserData: d.Get("cloudinit_userdata").(string),
+			Enabled:       cloudInitEnabled,
+			UserData:      d.Get("cloudinit_userdata").(string),
+			NetworkConfig: d.Get("cloudinit_networkconfig").(string),
+		}
+		if _, ok := d.GetOk("cloudinit_guest_variables"); ok {
+			userData, err := renderGuestTemplate(cloudInit.UserData, pool.Name, pool.Seed, d.Get("cloudinit_guest_variables.0.ip_range_start").(string))
+			if err != nil {
+				return nil, err
+			}
+			cloudInit.UserData = userData
 		}
 		guestProfile.CloudInit = &cloudInit
 	}
@@ -274,7 +368,7 @@ func poolFromResource(d *schema.ResourceData) *rest.Pool {
 			pool.GuestProfile.BrokerOptions.Connections = connections
 		}
 	}
-	return &pool
+	return &pool, nil
 }
 
 func resourceGuestPoolCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
@@ -282,7 +376,13 @@ func resourceGuestPoolCreate(ctx context.Context, d *schema.ResourceData, m inte
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	pool := poolFromResource(d)
+	pool, err := poolFromResource(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := applyHostDevices(client, d, pool); err != nil {
+		return diag.FromErr(err)
+	}
 
 	template, err := client.GetTemplate(pool.GuestProfile.TemplateName)
 	if err != nil {
@@ -305,10 +405,12 @@ func resourceGuestPoolCreate(ctx context.Context, d *schema.ResourceData, m inte
 	if err != nil {
 		return diag.FromErr(err)
 	}
+	d.SetId(pool.ID)
 	if d.Get("wait_for_build").(bool) {
-		pool.WaitForPoolWithContext(ctx, client, "tracking", 60*time.Minute)
+		if err := waitForPoolBuild(ctx, client, d, pool.ID, d.Timeout(schema.TimeoutCreate)); err != nil {
+			return diag.FromErr(err)
+		}
 	}
-	d.SetId(pool.ID)
 	return resourceGuestPoolRead(ctx, d, m)
 }
 
@@ -326,20 +428,53 @@ func resourceGuestPoolRead(ctx context.Context, d *schema.ResourceData, m interf
 	}
 
 	d.Set("name", pool.Name)
-	d.Set("cpu", pool.GuestProfile.CPU[0])
-	d.Set("memory", pool.GuestProfile.Mem[0])
+	d.Set("description", pool.Description)
+	labels, _ := tagLabels(pool.Tags)
+	d.Set("labels", flattenLabels(labels))
+	setGuestProfileRange(d, "cpu", "cpu_min", "cpu_max", pool.GuestProfile.CPU)
+	setGuestProfileRange(d, "memory", "memory_min", "memory_max", pool.GuestProfile.Mem)
 	d.Set("gpu", pool.GuestProfile.Gpu)
 	d.Set("persistent", pool.GuestProfile.Persistent)
 	d.Set("inject_agent", pool.InjectAgent)
 	d.Set("template", pool.GuestProfile.TemplateName)
+	interfaces, err := poolInterfaceOverrides(client, pool)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if interfaces != nil || d.Get("interface.#").(int) == 0 {
+		d.Set("interface", interfaces)
+	}
 	d.Set("profile", pool.ProfileID)
 	d.Set("seed", pool.Seed)
+	guests, err := listPoolGuests(client, pool.ID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	guestNames := make([]string, len(guests))
+	for i, guest := range guests {
+		guestNames[i] = guest.Name
+	}
+	d.Set("guest_names", guestNames)
 	d.Set("storage_type", pool.StorageType)
 	d.Set("storage_id", pool.StorageID)
-	d.Set("density", pool.Density)
+	d.Set("active_density", pool.Density)
+	// the configured density only applies outside the schedule windows
+	if d.Get("schedule.#").(int) == 0 {
+		d.Set("density", pool.Density)
+	}
 	if pool.GuestProfile.CloudInit != nil {
 		d.Set("cloudinit_enabled", pool.GuestProfile.CloudInit.Enabled)
-		d.Set("cloudinit_userdata", pool.GuestProfile.CloudInit.UserData)
+		userData := pool.GuestProfile.CloudInit.UserData
+		if _, ok := d.GetOk("cloudinit_guest_variables"); ok {
+			// keep the userdata from state when it still renders to the template stored in the pool
+			stateUserData := d.Get("cloudinit_userdata").(string)
+			rendered, err := renderGuestTemplate(stateUserData, pool.Name, pool.Seed, d.Get("cloudinit_guest_variables.0.ip_range_start").(string))
+			if err == nil && rendered == userData {
+				userData = stateUserData
+			}
+		}
+		d.Set("cloudinit_userdata", userData)
+		d.Set("cloudinit_networkconfig", pool.GuestProfile.CloudInit.NetworkConfig)
 	}
 
 	if pool.Backup != nil {
@@ -349,7 +484,24 @@ func resourceGuestPoolRead(ctx context.Context, d *schema.ResourceData, m interf
 			"target":    pool.Backup.TargetStorageID,
 		}})
 	}
-	if pool.PoolAffinity != nil && len(pool.PoolAffinity.AllowedHostIDs) > 0 {
+	if d.Get("retain_disks_on_destroy").(bool) {
+		disks, err := guestPoolRetainedDisks(client, pool)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		d.Set("retained_disks", flattenRetainedGuestDisks(disks))
+	} else {
+		d.Set("retained_disks", nil)
+	}
+	d.Set("gpu_profile", poolGpuProfile(pool.GuestProfile.HostDevices))
+	pciDevices, usbDevices, err := readHostDevices(client, d, pool)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	d.Set("pci_device", pciDevices)
+	d.Set("usb_device", usbDevices)
+	// allowed hosts narrowed down to the hosts with the requested devices stay as configured
+	if pool.PoolAffinity != nil && len(pool.PoolAffinity.AllowedHostIDs) > 0 && !hostDevicesRequested(d) {
 		d.Set("allowed_hosts", pool.PoolAffinity.AllowedHostIDs)
 	}
 
@@ -383,7 +535,19 @@ func resourceGuestPoolUpdate(ctx context.Context, d *schema.ResourceData, m inte
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	pool := poolFromResource(d)
+	pool, err := poolFromResource(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	// tags set outside terraform are kept, only the label tags are managed here
+	current, err := client.GetPool(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	pool.Tags = keepUnmanagedTags(current.Tags, pool.Tags, labelTagPrefix)
+	if err := applyHostDevices(client, d, pool); err != nil {
+		return diag.FromErr(err)
+	}
 
 	template, err := client.GetTemplate(pool.GuestProfile.TemplateName)
 	if err != nil {
@@ -397,11 +561,41 @@ func resourceGuestPoolUpdate(ctx context.Context, d *schema.ResourceData, m inte
 	if len(pool.GuestProfile.Mem) != 2 {
 		pool.GuestProfile.Mem = []int{template.Mem, template.Mem}
 	}
+	// an empty list does not clear the interface overrides, send the interfaces of the template
+	if len(pool.GuestProfile.Interfaces) == 0 {
+		for _, iface := range template.Interfaces {
+			pool.GuestProfile.Interfaces = append(pool.GuestProfile.Interfaces, &rest.PoolInterface{
+				Emulation: iface.Emulation,
+				Network:   iface.Network,
+				Vlan:      iface.Vlan,
+			})
+		}
+	}
 	_, err = pool.Update(client)
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	return resourceGuestPoolRead(ctx, d, m)
+	var diags diag.Diagnostics
+	if d.HasChange("template") && d.Get("rollout.#").(int) > 0 {
+		skipped, err := rolloutGuestPool(ctx, client, d, pool.GuestProfile.TemplateName, d.Timeout(schema.TimeoutUpdate))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if len(skipped) > 0 {
+			previous, _ := d.GetChange("template")
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  fmt.Sprintf("%d guests were not rebuilt because users are logged in", len(skipped)),
+				Detail:   fmt.Sprintf("%s still run the previous template %s.", strings.Join(skipped, ", "), previous),
+			})
+		}
+	}
+	if d.Get("wait_for_build").(bool) && d.HasChanges("active_density", "template", "cpu", "memory", "cpu_min", "cpu_max", "memory_min", "memory_max", "interface") {
+		if err := waitForPoolBuild(ctx, client, d, d.Id(), d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return append(diags, resourceGuestPoolRead(ctx, d, m)...)
 }
 
 func resourceGuestPoolDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
@@ -409,10 +603,18 @@ func resourceGuestPoolDelete(ctx context.Context, d *schema.ResourceData, m inte
 	if err != nil {
 		return diag.FromErr(err)
 	}
+	if err := checkDeletionProtection(d); err != nil {
+		return diag.FromErr(err)
+	}
 	pool, err := client.GetPool(d.Id())
 	if err != nil {
 		return diag.FromErr(err)
 	}
+	if d.Get("retain_disks_on_destroy").(bool) {
+		if err := copyGuestPoolDisks(ctx, client, pool, d.Timeout(schema.TimeoutDelete)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
 	err = pool.Delete(client)
 	if err != nil {
 		return diag.FromErr(err)
@@ -437,3 +639,32 @@ func resourceGuestPoolDelete(ctx context.Context, d *schema.ResourceData, m inte
 	}
 	return diag.Diagnostics{}
 }
+
+// poolInterfaceOverrides returns the interfaces of the pool when they differ from the interfaces of
+// its template, so pools that inherit the template networks have no interface blocks
+func poolInterfaceOverrides(client *rest.Client, pool *rest.Pool) ([]interface{}, error) {
+	if len(pool.GuestProfile.Interfaces) == 0 {
+		return nil, nil
+	}
+	template, err := client.GetTemplate(pool.GuestProfile.TemplateName)
+	if err != nil && !strings.Contains(err.Error(), "\"error\": 404") {
+		return nil, err
+	}
+	inherited := err == nil && len(template.Interfaces) == len(pool.GuestProfile.Interfaces)
+	interfaces := make([]interface{}, len(pool.GuestProfile.Interfaces))
+	for i, iface := range pool.GuestProfile.Interfaces {
+		if inherited {
+			tmpl := template.Interfaces[i]
+			inherited = tmpl.Network == iface.Network && tmpl.Vlan == vlanID(iface.Vlan) && tmpl.Emulation == iface.Emulation
+		}
+		interfaces[i] = map[string]interface{}{
+			"network":   iface.Network,
+			"vlan":      vlanID(iface.Vlan),
+			"emulation": iface.Emulation,
+		}
+	}
+	if inherited {
+		return nil, nil
+	}
+	return interfaces, nil
+}
@@ -0,0 +1,156 @@
+package hiveio
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+// TestGuestPoolCreate creates a guest pool and checks what was sent to the api
+func TestGuestPoolCreate(t *testing.T) {
+	hive, client := newFakeHive(t)
+	hive.templates["ubuntu"] = rest.Template{Name: "ubuntu", Vcpu: 2, Mem: 2048, OS: "linux", DisplayDriver: "cirrus"}
+
+	r := resourceGuestPool()
+	d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"name":     "desktops",
+		"density":  []interface{}{1, 2},
+		"template": "ubuntu",
+		"profile":  "default",
+		"seed":     "DESK",
+		"labels":   map[string]interface{}{"team": "desktop"},
+	})
+	if diags := r.CreateContext(context.Background(), d, client); diags.HasError() {
+		t.Fatalf("create failed: %v", diags)
+	}
+
+	pool := hive.poolByName("desktops")
+	if d.Id() != pool.ID {
+		t.Errorf("expected id %s, got %s", pool.ID, d.Id())
+	}
+	if pool.Seed != "DESK" || pool.Type != "vdi" {
+		t.Errorf("unexpected seed %q or type %q", pool.Seed, pool.Type)
+	}
+	if !slices.Equal(pool.Density, []int{1, 2}) {
+		t.Errorf("expected density [1 2], got %v", pool.Density)
+	}
+	if !slices.Equal(pool.GuestProfile.CPU, []int{2, 2}) || !slices.Equal(pool.GuestProfile.Mem, []int{2048, 2048}) {
+		t.Errorf("expected the template cpu and memory, got %v and %v", pool.GuestProfile.CPU, pool.GuestProfile.Mem)
+	}
+	if !slices.Equal(pool.Tags, []string{"label:team=desktop"}) {
+		t.Errorf("expected the label tags, got %v", pool.Tags)
+	}
+	if d.Get("labels.team") != "desktop" {
+		t.Errorf("labels were not read back: %v", d.Get("labels"))
+	}
+}
+
+func TestPoolInterfaceOverrides(t *testing.T) {
+	template := rest.Template{Name: "ubuntu", Interfaces: []*rest.TemplateInterface{
+		{Network: "prod", Vlan: 10, Emulation: "virtio"},
+	}}
+	tests := []struct {
+		name       string
+		template   string
+		interfaces []*rest.PoolInterface
+		want       []interface{}
+	}{
+		{
+			name:     "none",
+			template: "ubuntu",
+		},
+		{
+			name:       "same as the template",
+			template:   "ubuntu",
+			interfaces: []*rest.PoolInterface{{Network: "prod", Vlan: float64(10), Emulation: "virtio"}},
+		},
+		{
+			name:       "other vlan",
+			template:   "ubuntu",
+			interfaces: []*rest.PoolInterface{{Network: "prod", Vlan: float64(20), Emulation: "virtio"}},
+			want:       []interface{}{map[string]interface{}{"network": "prod", "vlan": 20, "emulation": "virtio"}},
+		},
+		{
+			name:     "more interfaces",
+			template: "ubuntu",
+			interfaces: []*rest.PoolInterface{
+				{Network: "prod", Vlan: float64(10), Emulation: "virtio"},
+				{Network: "backup", Emulation: "e1000"},
+			},
+			want: []interface{}{
+				map[string]interface{}{"network": "prod", "vlan": 10, "emulation": "virtio"},
+				map[string]interface{}{"network": "backup", "vlan": 0, "emulation": "e1000"},
+			},
+		},
+		{
+			name:       "missing template",
+			template:   "deleted",
+			interfaces: []*rest.PoolInterface{{Network: "prod", Vlan: float64(10), Emulation: "virtio"}},
+			want:       []interface{}{map[string]interface{}{"network": "prod", "vlan": 10, "emulation": "virtio"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hive, client := newFakeHive(t)
+			hive.templates["ubuntu"] = template
+			pool := &rest.Pool{GuestProfile: &rest.PoolGuestProfile{TemplateName: tt.template, Interfaces: tt.interfaces}}
+			got, err := poolInterfaceOverrides(client, pool)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestGuestPoolUpdateClearsInterfaces removes the interface blocks of a pool and checks that the
+// interfaces of the template are sent and the overrides are gone
+func TestGuestPoolUpdateClearsInterfaces(t *testing.T) {
+	hive, client := newFakeHive(t)
+	hive.templates["ubuntu"] = rest.Template{Name: "ubuntu", Vcpu: 2, Mem: 2048, OS: "linux", Interfaces: []*rest.TemplateInterface{
+		{Network: "prod", Vlan: 10, Emulation: "virtio"},
+	}}
+	hive.pools[testVMID] = rest.Pool{
+		ID:      testVMID,
+		Name:    "desktops",
+		Type:    "vdi",
+		Seed:    "DESK",
+		Density: []int{1, 2},
+		GuestProfile: &rest.PoolGuestProfile{
+			TemplateName: "ubuntu",
+			CPU:          []int{2, 2},
+			Mem:          []int{2048, 2048},
+			Interfaces:   []*rest.PoolInterface{{Network: "lab", Vlan: float64(30), Emulation: "e1000"}},
+		},
+	}
+
+	r := resourceGuestPool()
+	d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"name":     "desktops",
+		"density":  []interface{}{1, 2},
+		"template": "ubuntu",
+		"profile":  "default",
+		"seed":     "DESK",
+	})
+	d.SetId(testVMID)
+	if diags := r.UpdateContext(context.Background(), d, client); diags.HasError() {
+		t.Fatalf("update failed: %v", diags)
+	}
+
+	pool, _ := hive.pool(testVMID)
+	if len(pool.GuestProfile.Interfaces) != 1 {
+		t.Fatalf("expected the template interface, got %d interfaces", len(pool.GuestProfile.Interfaces))
+	}
+	if iface := pool.GuestProfile.Interfaces[0]; iface.Network != "prod" || vlanID(iface.Vlan) != 10 || iface.Emulation != "virtio" {
+		t.Errorf("expected the template interface, got %+v", iface)
+	}
+	if n := d.Get("interface.#").(int); n != 0 {
+		t.Errorf("expected no interface overrides after the update, got %d", n)
+	}
+}
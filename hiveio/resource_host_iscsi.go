@@ -15,7 +15,7 @@ func resourceHostIscsi() *schema.Resource {
 		ReadContext:   resourceHostIscsiRead,
 		DeleteContext: resourceHostIscsiDelete,
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importHostIscsi,
 		},
 		Description: "Adds an iscsi disk to a host in the Hive cluster.",
 		Schema: map[string]*schema.Schema{
@@ -17,7 +17,7 @@ func resourceHostNetwork() *schema.Resource {
 		UpdateContext: resourceHostNetworkUpdate,
 		DeleteContext: resourceHostNetworkDelete,
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importHostNetwork,
 		},
 
 		Schema: map[string]*schema.Schema{
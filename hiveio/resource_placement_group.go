@@ -0,0 +1,254 @@
+package hiveio
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func resourcePlacementGroup() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Checks that the guests of a set of vms and guest pools are spread across hosts or packed onto one host. Hive has no placement policies, so the group is kept in the terraform state only. Violations are reported as warnings and the plan shows a change to violations when they differ from the state; use allowed_hosts on the members to move guests.",
+		CreateContext: resourcePlacementGroupCreate,
+		ReadContext:   resourcePlacementGroupRead,
+		UpdateContext: resourcePlacementGroupUpdate,
+		DeleteContext: resourcePlacementGroupDelete,
+		CustomizeDiff: diffPlacementGroup,
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Description: "The name of the placement group.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"policy": {
+				Type:         schema.TypeString,
+				Description:  "spread keeps every guest of the members on a different host and pack keeps them all on the same host. A spread group whose members can have more guests than there are hosts is rejected.",
+				Default:      "spread",
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice([]string{"spread", "pack"}, false),
+			},
+			"members": {
+				Type:        schema.TypeSet,
+				Description: "The ids of the hiveio_virtual_machine and hiveio_guest_pool resources in the group.",
+				Required:    true,
+				MinItems:    1,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"placement": {
+				Type:        schema.TypeList,
+				Description: "The host of each running guest of the members.",
+				Computed:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"pool_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"guest_name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"host_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+			"violations": {
+				Type:        schema.TypeList,
+				Description: "The ways the members do not follow the policy.",
+				Computed:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"provider_override": &providerOverride,
+		},
+	}
+}
+
+// placementGuests lists the guests of the member pools that are placed on a host
+func placementGuests(client *rest.Client, members []string) ([]rest.Guest, error) {
+	var placed []rest.Guest
+	for _, member := range members {
+		guests, err := client.ListGuests("poolId=" + url.QueryEscape(member))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list guests for pool %s: %w", member, err)
+		}
+		for _, guest := range guests {
+			if guest.PoolID == member && guest.Hostid != "" {
+				placed = append(placed, guest)
+			}
+		}
+	}
+	sort.Slice(placed, func(i, j int) bool {
+		return placed[i].Name < placed[j].Name
+	})
+	return placed, nil
+}
+
+// placementViolations checks the hosts of the guests against the policy
+func placementViolations(policy string, guests []rest.Guest) []string {
+	byHost := map[string][]string{}
+	for _, guest := range guests {
+		byHost[guest.Hostid] = append(byHost[guest.Hostid], guest.Name)
+	}
+	violations := []string{}
+	switch policy {
+	case "spread":
+		for _, host := range sortedKeys(byHost) {
+			if names := byHost[host]; len(names) > 1 {
+				violations = append(violations, fmt.Sprintf("%s share host %s", strings.Join(names, ", "), host))
+			}
+		}
+	case "pack":
+		if len(byHost) > 1 {
+			hosts := sortedKeys(byHost)
+			violations = append(violations, fmt.Sprintf("guests are running on %d hosts: %s", len(hosts), strings.Join(hosts, ", ")))
+		}
+	}
+	return violations
+}
+
+// diffPlacementGroup rejects spread groups with more guests than hosts and plans a change to
+// violations only when the guests no longer match the violations in the state
+func diffPlacementGroup(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	client, ok := m.(*rest.Client)
+	if _, override := d.GetOk("provider_override"); override || !ok {
+		if d.HasChanges("members", "policy") {
+			return d.SetNewComputed("violations")
+		}
+		return nil
+	}
+	if !d.NewValueKnown("members") || !d.NewValueKnown("policy") {
+		return d.SetNewComputed("violations")
+	}
+	var members []string
+	for _, member := range d.Get("members").(*schema.Set).List() {
+		if member.(string) == "" {
+			return d.SetNewComputed("violations")
+		}
+		members = append(members, member.(string))
+	}
+	sort.Strings(members)
+	policy := d.Get("policy").(string)
+
+	if policy == "spread" && d.HasChanges("members", "policy") {
+		hosts, err := client.ListHosts("")
+		if err != nil {
+			return fmt.Errorf("failed to list hosts: %w", err)
+		}
+		maxGuests := 0
+		for _, member := range members {
+			pool, err := client.GetPool(member)
+			if err != nil {
+				// members created in the same apply are checked when they exist
+				continue
+			}
+			if len(pool.Density) > 1 {
+				maxGuests += pool.Density[1]
+			} else {
+				maxGuests++
+			}
+		}
+		if maxGuests > len(hosts) {
+			return fmt.Errorf("the members can have %d guests but there are %d hosts, spread needs a host for every guest", maxGuests, len(hosts))
+		}
+	}
+
+	guests, err := placementGuests(client, members)
+	if err != nil {
+		return err
+	}
+	violations := placementViolations(policy, guests)
+	var current []string
+	for _, v := range d.Get("violations").([]interface{}) {
+		current = append(current, v.(string))
+	}
+	if slices.Equal(current, violations) {
+		return nil
+	}
+	return d.SetNew("violations", violations)
+}
+
+func resourcePlacementGroupCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	for _, member := range d.Get("members").(*schema.Set).List() {
+		if _, err := client.GetPool(member.(string)); err != nil {
+			return diag.Errorf("placement group member %s: %s", member, err)
+		}
+	}
+	d.SetId(d.Get("name").(string))
+	return resourcePlacementGroupRead(ctx, d, m)
+}
+
+func resourcePlacementGroupRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	var members []string
+	for _, member := range d.Get("members").(*schema.Set).List() {
+		members = append(members, member.(string))
+	}
+	sort.Strings(members)
+	guests, err := placementGuests(client, members)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	placement := make([]interface{}, len(guests))
+	for i, guest := range guests {
+		placement[i] = map[string]interface{}{
+			"pool_id":    guest.PoolID,
+			"guest_name": guest.Name,
+			"host_id":    guest.Hostid,
+		}
+	}
+	d.Set("placement", placement)
+
+	violations := placementViolations(d.Get("policy").(string), guests)
+	d.Set("violations", violations)
+	var diags diag.Diagnostics
+	for _, violation := range violations {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("placement group %s does not follow the %s policy", d.Id(), d.Get("policy").(string)),
+			Detail:   violation,
+		})
+	}
+	return diags
+}
+
+func resourcePlacementGroupUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if d.HasChange("members") {
+		client, err := getClient(d, m)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		for _, member := range d.Get("members").(*schema.Set).List() {
+			if _, err := client.GetPool(member.(string)); err != nil {
+				return diag.Errorf("placement group member %s: %s", member, err)
+			}
+		}
+	}
+	return resourcePlacementGroupRead(ctx, d, m)
+}
+
+func resourcePlacementGroupDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return diag.Diagnostics{}
+}
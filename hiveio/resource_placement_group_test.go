@@ -0,0 +1,66 @@
+package hiveio
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func TestPlacementViolations(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		guests []rest.Guest
+		want   []string
+	}{
+		{
+			name:   "spread without guests",
+			policy: "spread",
+			want:   []string{},
+		},
+		{
+			name:   "spread on different hosts",
+			policy: "spread",
+			guests: []rest.Guest{{Name: "WEB001", Hostid: "host1"}, {Name: "WEB002", Hostid: "host2"}},
+			want:   []string{},
+		},
+		{
+			name:   "spread sharing hosts",
+			policy: "spread",
+			guests: []rest.Guest{
+				{Name: "WEB001", Hostid: "host2"},
+				{Name: "WEB002", Hostid: "host2"},
+				{Name: "DB001", Hostid: "host1"},
+				{Name: "DB002", Hostid: "host1"},
+				{Name: "DB003", Hostid: "host3"},
+			},
+			want: []string{"DB001, DB002 share host host1", "WEB001, WEB002 share host host2"},
+		},
+		{
+			name:   "pack on one host",
+			policy: "pack",
+			guests: []rest.Guest{{Name: "WEB001", Hostid: "host1"}, {Name: "WEB002", Hostid: "host1"}},
+			want:   []string{},
+		},
+		{
+			name:   "pack without guests",
+			policy: "pack",
+			want:   []string{},
+		},
+		{
+			name:   "pack on several hosts",
+			policy: "pack",
+			guests: []rest.Guest{{Name: "WEB001", Hostid: "host2"}, {Name: "WEB002", Hostid: "host1"}, {Name: "WEB003", Hostid: "host2"}},
+			want:   []string{"guests are running on 2 hosts: host1, host2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := placementViolations(tt.policy, tt.guests)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
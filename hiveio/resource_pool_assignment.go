@@ -0,0 +1,282 @@
+package hiveio
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func resourcePoolAssignment() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Assigns a realm user or AD group to a virtual machine, or a user to a specific guest in a persistent guest pool. The Hive api only assigns whole standalone pools, so a guest pool needs guest_name. A virtual machine has a single assignment, so only one hiveio_pool_assignment without guest_name can exist for each and creating one for a virtual machine or guest that is already assigned fails. Changing the user, group or guest reassigns in place.",
+		CreateContext: resourcePoolAssignmentCreate,
+		ReadContext:   resourcePoolAssignmentRead,
+		UpdateContext: resourcePoolAssignmentUpdate,
+		DeleteContext: resourcePoolAssignmentDelete,
+		CustomizeDiff: validatePoolAssignment,
+		Importer: &schema.ResourceImporter{
+			StateContext: importPoolAssignment,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"pool_id": {
+				Type:        schema.TypeString,
+				Description: "The id of the hiveio_guest_pool or hiveio_virtual_machine.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"realm": {
+				Type:        schema.TypeString,
+				Description: "The realm of the user or group.",
+				Required:    true,
+			},
+			"username": {
+				Type:         schema.TypeString,
+				Description:  "The user to assign.",
+				Optional:     true,
+				ExactlyOneOf: []string{"username", "ad_group"},
+			},
+			"ad_group": {
+				Type:          schema.TypeString,
+				Description:   "The AD group to assign. Groups can only be assigned to a whole virtual machine.",
+				Optional:      true,
+				ConflictsWith: []string{"guest_name"},
+			},
+			"guest_name": {
+				Type:        schema.TypeString,
+				Description: "Assign the user to this guest of the pool instead of to the pool. Required for guest pools.",
+				Optional:    true,
+			},
+			"provider_override": &providerOverride,
+		},
+	}
+}
+
+func poolAssignmentID(poolID, guestName string) string {
+	if guestName == "" {
+		return poolID
+	}
+	return poolID + "/" + guestName
+}
+
+// importPoolAssignment accepts <pool id> for pool assignments and <pool id>/<guest name> for guests
+func importPoolAssignment(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	poolID, guestName, _ := strings.Cut(d.Id(), "/")
+	if poolID == "" {
+		return nil, fmt.Errorf("unexpected import id %q, expected pool_id or pool_id/guest_name", d.Id())
+	}
+	d.Set("pool_id", poolID)
+	d.Set("guest_name", guestName)
+	return []*schema.ResourceData{d}, nil
+}
+
+func assignPool(client *rest.Client, d *schema.ResourceData) error {
+	poolID := d.Get("pool_id").(string)
+	realm := d.Get("realm").(string)
+	username := d.Get("username").(string)
+	if guestName := d.Get("guest_name").(string); guestName != "" {
+		if _, err := client.AssignGuest(poolID, username, realm, guestName); err != nil {
+			return fmt.Errorf("failed to assign %s to %s: %w", username, guestName, err)
+		}
+		return nil
+	}
+	pool, err := client.GetPool(poolID)
+	if err != nil {
+		return err
+	}
+	if err := checkPoolAssignable(pool); err != nil {
+		return err
+	}
+	if err := pool.Assign(client, realm, username, d.Get("ad_group").(string)); err != nil {
+		return fmt.Errorf("failed to assign pool %s: %w", pool.Name, err)
+	}
+	return nil
+}
+
+func releasePool(client *rest.Client, poolID, username, guestName string) error {
+	if guestName != "" {
+		err := client.ReleaseGuest(poolID, username, guestName)
+		if err != nil && !strings.Contains(err.Error(), "\"error\": 404") {
+			return fmt.Errorf("failed to release %s: %w", guestName, err)
+		}
+		return nil
+	}
+	pool, err := client.GetPool(poolID)
+	if err != nil && strings.Contains(err.Error(), "\"error\": 404") {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return pool.DeleteAssignment(client)
+}
+
+// checkPoolAssignable fails for pools that cannot take a pool level assignment. The Hive api only
+// assigns users and groups to whole standalone pools, users of a guest pool are assigned to its guests.
+func checkPoolAssignable(pool *rest.Pool) error {
+	if pool.Type != "standalone" {
+		return fmt.Errorf("%s is a %s pool, users and groups can only be assigned to a whole hiveio_virtual_machine, set guest_name to assign a user to a guest of a guest pool", pool.Name, pool.Type)
+	}
+	return nil
+}
+
+// validatePoolAssignment rejects pool level assignments to guest pools at plan time when the
+// cluster can be reached with the provider configuration
+func validatePoolAssignment(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("pool_id") || !d.NewValueKnown("guest_name") || d.Get("guest_name").(string) != "" {
+		return nil
+	}
+	if _, ok := d.GetOk("provider_override"); ok || !d.HasChanges("pool_id", "guest_name") {
+		return nil
+	}
+	client, ok := m.(*rest.Client)
+	if !ok {
+		return nil
+	}
+	pool, err := client.GetPool(d.Get("pool_id").(string))
+	if err != nil && strings.Contains(err.Error(), "\"error\": 404") {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read pool %s: %w", d.Get("pool_id").(string), err)
+	}
+	return checkPoolAssignable(pool)
+}
+
+// checkUnassigned fails when the pool or guest already has an assignment, which creating another
+// assignment would silently replace
+func checkUnassigned(client *rest.Client, poolID, guestName string) error {
+	if guestName != "" {
+		guest, err := client.GetGuest(guestName)
+		if err != nil {
+			return err
+		}
+		if guest.Username != "" {
+			return fmt.Errorf("guest %s is already assigned to %s, import it as hiveio_pool_assignment %s", guestName, guest.Username, poolAssignmentID(poolID, guestName))
+		}
+		return nil
+	}
+	pool, err := client.GetPool(poolID)
+	if err != nil {
+		return err
+	}
+	if pool.Assignment != nil && (pool.Assignment.Username != "" || pool.Assignment.ADGroup != "") {
+		assignee := pool.Assignment.Username
+		if assignee == "" {
+			assignee = pool.Assignment.ADGroup
+		}
+		return fmt.Errorf("pool %s is already assigned to %s, import it as hiveio_pool_assignment %s", pool.Name, assignee, poolID)
+	}
+	return nil
+}
+
+func resourcePoolAssignmentCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := checkUnassigned(client, d.Get("pool_id").(string), d.Get("guest_name").(string)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := assignPool(client, d); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(poolAssignmentID(d.Get("pool_id").(string), d.Get("guest_name").(string)))
+	return resourcePoolAssignmentRead(ctx, d, m)
+}
+
+func resourcePoolAssignmentRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	poolID := d.Get("pool_id").(string)
+	if guestName := d.Get("guest_name").(string); guestName != "" {
+		guest, err := client.GetGuest(guestName)
+		if err != nil && strings.Contains(err.Error(), "\"error\": 404") {
+			d.SetId("")
+			return diag.Diagnostics{}
+		} else if err != nil {
+			return diag.FromErr(err)
+		}
+		if guest.PoolID != poolID || guest.Username == "" {
+			d.SetId("")
+			return diag.Diagnostics{}
+		}
+		d.Set("realm", guest.Realm)
+		d.Set("username", guest.Username)
+		d.Set("ad_group", "")
+		return diag.Diagnostics{}
+	}
+
+	pool, err := client.GetPool(poolID)
+	if err != nil && strings.Contains(err.Error(), "\"error\": 404") {
+		d.SetId("")
+		return diag.Diagnostics{}
+	} else if err != nil {
+		return diag.FromErr(err)
+	}
+	if pool.Assignment == nil || (pool.Assignment.Username == "" && pool.Assignment.ADGroup == "") {
+		d.SetId("")
+		return diag.Diagnostics{}
+	}
+	d.Set("realm", pool.Assignment.Realm)
+	d.Set("username", pool.Assignment.Username)
+	d.Set("ad_group", pool.Assignment.ADGroup)
+	return diag.Diagnostics{}
+}
+
+func resourcePoolAssignmentUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	poolID := d.Get("pool_id").(string)
+	oldUsername, _ := d.GetChange("username")
+	oldRealm, _ := d.GetChange("realm")
+	oldGuestName, _ := d.GetChange("guest_name")
+	guestName := d.Get("guest_name").(string)
+	switch {
+	case oldGuestName == "" && guestName == "":
+		// the pool has one assignment, assigning replaces it
+		if err := assignPool(client, d); err != nil {
+			return diag.FromErr(err)
+		}
+	case oldGuestName == guestName:
+		// a guest is released before it can be assigned again, the old user is put back if that fails
+		if err := releasePool(client, poolID, oldUsername.(string), guestName); err != nil {
+			return diag.FromErr(err)
+		}
+		if err := assignPool(client, d); err != nil {
+			if _, restoreErr := client.AssignGuest(poolID, oldUsername.(string), oldRealm.(string), guestName); restoreErr != nil {
+				return diag.Errorf("%s, and failed to assign %s back to %s: %s", err, guestName, oldUsername, restoreErr)
+			}
+			return diag.FromErr(err)
+		}
+	default:
+		if err := checkUnassigned(client, poolID, guestName); err != nil {
+			return diag.FromErr(err)
+		}
+		if err := assignPool(client, d); err != nil {
+			return diag.FromErr(err)
+		}
+		if err := releasePool(client, poolID, oldUsername.(string), oldGuestName.(string)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	d.SetId(poolAssignmentID(d.Get("pool_id").(string), d.Get("guest_name").(string)))
+	return resourcePoolAssignmentRead(ctx, d, m)
+}
+
+func resourcePoolAssignmentDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := releasePool(client, d.Get("pool_id").(string), d.Get("username").(string), d.Get("guest_name").(string)); err != nil {
+		return diag.FromErr(err)
+	}
+	return diag.Diagnostics{}
+}
@@ -0,0 +1,62 @@
+package hiveio
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func TestValidatePoolAssignment(t *testing.T) {
+	hive, client := newFakeHive(t)
+	hive.pools["vm1"] = rest.Pool{ID: "vm1", Name: "kubuntu", Type: "standalone"}
+	hive.pools["pool1"] = rest.Pool{ID: "pool1", Name: "desktops", Type: "vdi"}
+
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"user on a virtual machine", `{"pool_id": "vm1", "realm": "EXAMPLE", "username": "jdoe"}`, ""},
+		{"group on a virtual machine", `{"pool_id": "vm1", "realm": "EXAMPLE", "ad_group": "support"}`, ""},
+		{"group on a guest pool", `{"pool_id": "pool1", "realm": "EXAMPLE", "ad_group": "support"}`, "desktops is a vdi pool"},
+		{"user on a guest pool", `{"pool_id": "pool1", "realm": "EXAMPLE", "username": "jdoe"}`, "set guest_name"},
+		{"user on a guest of a guest pool", `{"pool_id": "pool1", "realm": "EXAMPLE", "username": "jdoe", "guest_name": "DESK001"}`, ""},
+		{"pool created in the same apply", `{"pool_id": "missing", "realm": "EXAMPLE", "username": "jdoe"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := planResource(t, resourcePoolAssignment(), nil, tt.config, client)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestPoolAssignmentCreateGuestPool checks that a guest pool is refused before anything is assigned
+// when the plan time check could not run
+func TestPoolAssignmentCreateGuestPool(t *testing.T) {
+	hive, client := newFakeHive(t)
+	hive.pools["pool1"] = rest.Pool{ID: "pool1", Name: "desktops", Type: "vdi"}
+
+	r := resourcePoolAssignment()
+	d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"pool_id":  "pool1",
+		"realm":    "EXAMPLE",
+		"ad_group": "support",
+	})
+	diags := r.CreateContext(context.Background(), d, client)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "desktops is a vdi pool") {
+		t.Fatalf("expected the guest pool to be refused, got %v", diags)
+	}
+	if slices.Contains(hive.requests, "POST /api/pool/pool1/assignment") {
+		t.Errorf("the guest pool should not be assigned")
+	}
+}
@@ -16,7 +16,7 @@ func resourceProfile() *schema.Resource {
 		UpdateContext: resourceProfileUpdate,
 		DeleteContext: resourceProfileDelete,
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importProfile,
 		},
 
 		Schema: map[string]*schema.Schema{
@@ -24,6 +24,8 @@ func resourceProfile() *schema.Resource {
 				Type:     schema.TypeString,
 				Required: true,
 			},
+			"description": descriptionSchema(),
+			"labels":      labelsSchema(),
 			"timezone": {
 				Description: "A timezone to inject to guests in the profile.",
 				Type:        schema.TypeString,
@@ -228,6 +230,7 @@ func profileFromResource(d *schema.ResourceData) *rest.Profile {
 	profile := &rest.Profile{
 		Name:     d.Get("name").(string),
 		Timezone: d.Get("timezone").(string),
+		Tags:     profileTags(d.Get("description").(string), expandLabels(d.Get("labels"))),
 	}
 
 	if d.Id() != "" {
@@ -328,6 +331,9 @@ func resourceProfileRead(ctx context.Context, d *schema.ResourceData, m interfac
 	}
 
 	d.Set("name", profile.Name)
+	description, labels := profileTagValues(profile.Tags)
+	d.Set("description", description)
+	d.Set("labels", flattenLabels(labels))
 	d.Set("timezone", profile.Timezone)
 
 	if profile.AdConfig != nil {
@@ -396,6 +402,12 @@ func resourceProfileUpdate(ctx context.Context, d *schema.ResourceData, m interf
 		return diag.FromErr(err)
 	}
 	profile := profileFromResource(d)
+	// tags set outside terraform are kept, only the label and description tags are managed here
+	current, err := client.GetProfile(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	profile.Tags = keepUnmanagedTags(current.Tags, profile.Tags, labelTagPrefix, descriptionTagPrefix)
 	_, err = profile.Update(client)
 	if err != nil {
 		return diag.FromErr(err)
@@ -17,7 +17,7 @@ func resourceSharedStorage() *schema.Resource {
 		ReadContext:   resourceSharedStorageRead,
 		DeleteContext: resourceSharedStorageDelete,
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importStoragePool,
 		},
 
 		Schema: map[string]*schema.Schema{
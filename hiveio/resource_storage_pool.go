@@ -20,7 +20,7 @@ func resourceStoragePool() *schema.Resource {
 		UpdateContext: resourceStoragePoolUpdate,
 		DeleteContext: resourceStoragePoolDelete,
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importStoragePool,
 		},
 
 		Schema: map[string]*schema.Schema{
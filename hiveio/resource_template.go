@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hive-io/hive-go-client/rest"
 )
@@ -17,8 +18,9 @@ func resourceTemplate() *schema.Resource {
 		ReadContext:   resourceTemplateRead,
 		UpdateContext: resourceTemplateUpdate,
 		DeleteContext: resourceTemplateDelete,
+		CustomizeDiff: customdiff.All(validateBootOrder, validateSecureBoot),
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importTemplate,
 		},
 
 		Schema: map[string]*schema.Schema{
@@ -27,6 +29,8 @@ func resourceTemplate() *schema.Resource {
 				Required: true,
 				ForceNew: true,
 			},
+			"description": descriptionSchema(),
+			"labels":      labelsSchema(),
 			"cpu": {
 				Type:     schema.TypeInt,
 				Default:  2,
@@ -42,6 +46,12 @@ func resourceTemplate() *schema.Resource {
 				Default:  "uefi",
 				Optional: true,
 			},
+			"secure_boot": {
+				Type:        schema.TypeBool,
+				Description: "Enable Secure Boot. Requires uefi firmware.",
+				Default:     false,
+				Optional:    true,
+			},
 			"display_driver": {
 				Type:     schema.TypeString,
 				Default:  "cirrus",
@@ -70,9 +80,10 @@ func resourceTemplate() *schema.Resource {
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
 						"type": {
-							Type:     schema.TypeString,
-							Default:  "Disk",
-							Optional: true,
+							Type:         schema.TypeString,
+							Default:      "Disk",
+							Optional:     true,
+							ValidateFunc: validateDiskType,
 						},
 						"storage_id": {
 							Type:     schema.TypeString,
@@ -99,6 +110,29 @@ func resourceTemplate() *schema.Resource {
 					},
 				},
 			},
+			"cdrom": {
+				Type:        schema.TypeList,
+				Description: "An iso image from a storage pool to attach as a cdrom.",
+				Optional:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"storage_id": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"filename": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"disk_driver": {
+							Type:     schema.TypeString,
+							Default:  "sata",
+							Optional: true,
+						},
+					},
+				},
+			},
+			"boot_order": &bootOrderSchema,
 			"interface": {
 				Type:     schema.TypeList,
 				Optional: true,
@@ -195,26 +229,44 @@ func templateFromResource(d *schema.ResourceData) rest.Template {
 		Vcpu:               d.Get("cpu").(int),
 		Mem:                d.Get("mem").(int),
 		Firmware:           d.Get("firmware").(string),
+		Secureboot:         d.Get("secure_boot").(bool),
 		DisplayDriver:      d.Get("display_driver").(string),
 		OS:                 d.Get("os").(string),
 		ManualAgentInstall: d.Get("manual_agent_install").(bool),
+		Description:        annotateDescription(d.Get("description").(string), expandLabels(d.Get("labels"))),
 	}
 
 	if d.Id() != "" {
 		template.Name = d.Id()
 	}
 
-	var disks []*rest.TemplateDisk
-	for i := 0; i < d.Get("disk.#").(int); i++ {
+	devices := make(map[string]*rest.TemplateDisk)
+	nDisks := d.Get("disk.#").(int)
+	for i := 0; i < nDisks; i++ {
 		prefix := fmt.Sprintf("disk.%d.", i)
-		disk := rest.TemplateDisk{
+		devices[fmt.Sprintf("disk.%d", i)] = &rest.TemplateDisk{
 			DiskDriver: d.Get(prefix + "disk_driver").(string),
 			Type:       d.Get(prefix + "type").(string),
 			StorageID:  d.Get(prefix + "storage_id").(string),
 			Filename:   d.Get(prefix + "filename").(string),
 			Format:     d.Get(prefix + "format").(string),
 		}
-		disks = append(disks, &disk)
+	}
+	nCdroms := d.Get("cdrom.#").(int)
+	for i := 0; i < nCdroms; i++ {
+		prefix := fmt.Sprintf("cdrom.%d.", i)
+		devices[fmt.Sprintf("cdrom.%d", i)] = &rest.TemplateDisk{
+			DiskDriver: d.Get(prefix + "disk_driver").(string),
+			Type:       cdromDiskType,
+			StorageID:  d.Get(prefix + "storage_id").(string),
+			Filename:   d.Get(prefix + "filename").(string),
+			Format:     "raw",
+		}
+	}
+
+	var disks []*rest.TemplateDisk
+	for _, key := range templateDeviceOrder(templateBootOrder(d), nDisks, nCdroms) {
+		disks = append(disks, devices[key])
 	}
 	template.Disks = disks
 
@@ -259,6 +311,14 @@ func templateFromResource(d *schema.ResourceData) rest.Template {
 	return template
 }
 
+func templateBootOrder(d *schema.ResourceData) []string {
+	var bootOrder []string
+	for _, v := range d.Get("boot_order").([]interface{}) {
+		bootOrder = append(bootOrder, v.(string))
+	}
+	return bootOrder
+}
+
 func resourceTemplateCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	client, err := getClient(d, m)
 	if err != nil {
@@ -295,16 +355,65 @@ func resourceTemplateRead(ctx context.Context, d *schema.ResourceData, m interfa
 	}
 
 	d.Set("name", template.Name)
+	description, labels := parseAnnotatedDescription(template.Description)
+	d.Set("description", description)
+	d.Set("labels", flattenLabels(labels))
 	d.Set("cpu", template.Vcpu)
 	d.Set("mem", template.Mem)
 	d.Set("firmware", template.Firmware)
+	d.Set("secure_boot", template.Secureboot)
 	d.Set("display_driver", template.DisplayDriver)
 	d.Set("os", template.OS)
 	d.Set("manual_agent_install", template.ManualAgentInstall)
 
-	disks := make([]map[string]interface{}, len(template.Disks))
+	var nDisks, nCdroms int
+	for _, disk := range template.Disks {
+		if isCdrom(disk.Type) {
+			nCdroms++
+		} else {
+			nDisks++
+		}
+	}
+	// disks are stored in boot order so use boot_order from state to put them back in place
+	bootOrder := []string{}
+	for _, key := range templateBootOrder(d) {
+		if _, index, err := parseBootDevice(key); err == nil && strings.HasPrefix(key, "disk.") && index < nDisks {
+			bootOrder = append(bootOrder, key)
+		} else if err == nil && strings.HasPrefix(key, "cdrom.") && index < nCdroms {
+			bootOrder = append(bootOrder, key)
+		}
+	}
+	keys := templateDeviceOrder(bootOrder, nDisks, nCdroms)
+	for i, disk := range template.Disks {
+		if isCdrom(disk.Type) != strings.HasPrefix(keys[i], "cdrom.") {
+			// the disks were reordered outside of terraform so read them as they are
+			bootOrder = []string{}
+			var diskIndex, cdromIndex int
+			for j, disk := range template.Disks {
+				if isCdrom(disk.Type) {
+					keys[j] = fmt.Sprintf("cdrom.%d", cdromIndex)
+					cdromIndex++
+				} else {
+					keys[j] = fmt.Sprintf("disk.%d", diskIndex)
+					diskIndex++
+				}
+			}
+			break
+		}
+	}
+	disks := make([]map[string]interface{}, nDisks)
+	cdroms := make([]map[string]interface{}, nCdroms)
 	for i, disk := range template.Disks {
-		disks[i] = map[string]interface{}{
+		kind, index, _ := parseBootDevice(keys[i])
+		if kind == "cdrom" {
+			cdroms[index] = map[string]interface{}{
+				"disk_driver": disk.DiskDriver,
+				"storage_id":  disk.StorageID,
+				"filename":    disk.Filename,
+			}
+			continue
+		}
+		disks[index] = map[string]interface{}{
 			"disk_driver": disk.DiskDriver,
 			"type":        disk.Type,
 			"storage_id":  disk.StorageID,
@@ -313,6 +422,8 @@ func resourceTemplateRead(ctx context.Context, d *schema.ResourceData, m interfa
 		}
 	}
 	d.Set("disk", disks)
+	d.Set("cdrom", cdroms)
+	d.Set("boot_order", bootOrder)
 
 	interfaces := make([]map[string]interface{}, len(template.Interfaces))
 	for i, iface := range template.Interfaces {
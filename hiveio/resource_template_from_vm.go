@@ -0,0 +1,270 @@
+package hiveio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func resourceTemplateFromVM() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Seals a virtual machine into a template. The vm is shut down, its disks are flattened into new qcow2 files and a template is registered with the hardware and broker settings of the vm. Destroying the resource deletes the template and the copied disks. Running sysprep or other cleanup in the guest is not available through the Hive api client and should be done before the template is made, for example from cloud-init.",
+		CreateContext: resourceTemplateFromVMCreate,
+		ReadContext:   resourceTemplateFromVMRead,
+		DeleteContext: resourceTemplateFromVMDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(60 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Description: "The name of the template.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"vm_id": {
+				Type:        schema.TypeString,
+				Description: "The id of the hiveio_virtual_machine to convert.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"storage_id": {
+				Type:        schema.TypeString,
+				Description: "The storage pool for the template disks. Defaults to the storage pool of each vm disk.",
+				Optional:    true,
+				ForceNew:    true,
+			},
+			"shutdown_vm": {
+				Type:        schema.TypeBool,
+				Description: "Shut down the vm before its disks are copied. Copying the disks of a running vm may not give a consistent image.",
+				Default:     true,
+				Optional:    true,
+				ForceNew:    true,
+			},
+			"cpu": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"mem": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"os": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"firmware": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"disk": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"storage_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"filename": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+			"provider_override": &providerOverride,
+		},
+	}
+}
+
+// guestStopped is true once the guest has powered off after a shutdown
+func guestStopped(guest rest.Guest) bool {
+	switch strings.ToLower(guest.GuestState) {
+	case "off", "stopped", "shutdown":
+		return true
+	}
+	return false
+}
+
+func resourceTemplateFromVMCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	pool, err := client.GetPool(d.Get("vm_id").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if pool.Type != "standalone" {
+		return diag.Errorf("%s is not a virtual machine", pool.Name)
+	}
+
+	if d.Get("shutdown_vm").(bool) {
+		guest, err := getPoolGuest(client, pool.ID)
+		if err != nil && !errors.Is(err, errGuestNotFound) {
+			return diag.FromErr(err)
+		}
+		if guest != nil && !guestStopped(*guest) {
+			if err := guest.Shutdown(client); err != nil {
+				return diag.Errorf("failed to shut down %s: %s", guest.Name, err)
+			}
+			if err := guest.WaitForGuestChange(ctx, client, d.Timeout(schema.TimeoutCreate), guestStopped); err != nil {
+				return diag.Errorf("waiting for %s to shut down: %s", guest.Name, err)
+			}
+		}
+	}
+
+	name := d.Get("name").(string)
+	template := rest.Template{
+		Name:          name,
+		OS:            pool.GuestProfile.OS,
+		Firmware:      pool.GuestProfile.Firmware,
+		DisplayDriver: pool.GuestProfile.Vga,
+		Gpu:           pool.GuestProfile.Gpu,
+		Secureboot:    pool.GuestProfile.Secureboot,
+		BrokerOptions: pool.GuestProfile.BrokerOptions,
+	}
+	if len(pool.GuestProfile.CPU) > 0 {
+		template.Vcpu = pool.GuestProfile.CPU[0]
+	}
+	if len(pool.GuestProfile.Mem) > 0 {
+		template.Mem = pool.GuestProfile.Mem[0]
+	}
+	for _, iface := range pool.GuestProfile.Interfaces {
+		template.Interfaces = append(template.Interfaces, &rest.TemplateInterface{
+			Network:   iface.Network,
+			Vlan:      vlanID(iface.Vlan),
+			Emulation: iface.Emulation,
+		})
+	}
+
+	var disks []interface{}
+	var copied []*rest.PoolDisk
+	for _, disk := range pool.GuestProfile.Disks {
+		if isCdrom(disk.Type) {
+			continue
+		}
+		storageID := d.Get("storage_id").(string)
+		if storageID == "" {
+			storageID = disk.StorageID
+		}
+		filename := fmt.Sprintf("%s-disk%d.qcow2", name, len(disks))
+		if err := cloneDisk(ctx, client, disk, storageID, filename, "full"); err != nil {
+			// nothing tracks the disks copied so far, they would block the next apply
+			err = fmt.Errorf("failed to copy disks from %s: %w", pool.Name, err)
+			return diag.FromErr(errors.Join(err, deleteClonedDisks(client, copied)))
+		}
+		copied = append(copied, &rest.PoolDisk{StorageID: storageID, Filename: filename})
+		template.Disks = append(template.Disks, &rest.TemplateDisk{
+			DiskDriver: disk.DiskDriver,
+			Filename:   filename,
+			Format:     "qcow2",
+			StorageID:  storageID,
+			Type:       disk.Type,
+		})
+		disks = append(disks, map[string]interface{}{
+			"storage_id": storageID,
+			"filename":   filename,
+		})
+	}
+	d.Set("disk", disks)
+
+	_, err = template.Create(client)
+	if err != nil {
+		return diag.FromErr(errors.Join(err, deleteClonedDisks(client, copied)))
+	}
+	if err := waitForTemplateAvailable(ctx, client, name, d.Timeout(schema.TimeoutCreate)); err != nil {
+		// the template is not in state, remove it with its disks so the next apply can make it again
+		return diag.FromErr(errors.Join(err, deleteTemplate(client, name), deleteClonedDisks(client, copied)))
+	}
+	d.SetId(name)
+	return resourceTemplateFromVMRead(ctx, d, m)
+}
+
+// waitForTemplateAvailable blocks until a newly registered template is available
+func waitForTemplateAvailable(ctx context.Context, client *rest.Client, name string, timeout time.Duration) error {
+	return retry.RetryContext(ctx, timeout, func() *retry.RetryError {
+		template, err := client.GetTemplate(name)
+		if err != nil {
+			return retry.NonRetryableError(err)
+		}
+		if template.State == "available" {
+			return nil
+		}
+		if err := sleepContext(ctx, 5*time.Second); err != nil {
+			return retry.NonRetryableError(err)
+		}
+		return retry.RetryableError(fmt.Errorf("template %s is %s: %s", name, template.State, template.StateMessage))
+	})
+}
+
+func deleteTemplate(client *rest.Client, name string) error {
+	template, err := client.GetTemplate(name)
+	if err != nil && strings.Contains(err.Error(), "\"error\": 404") {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := template.Delete(client); err != nil {
+		return fmt.Errorf("failed to delete template %s: %w", name, err)
+	}
+	return nil
+}
+
+func resourceTemplateFromVMRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	template, err := client.GetTemplate(d.Id())
+	if err != nil && strings.Contains(err.Error(), "\"error\": 404") {
+		d.SetId("")
+		return diag.Diagnostics{}
+	} else if err != nil {
+		return diag.FromErr(err)
+	}
+	d.Set("name", template.Name)
+	d.Set("cpu", template.Vcpu)
+	d.Set("mem", template.Mem)
+	d.Set("os", template.OS)
+	d.Set("firmware", template.Firmware)
+	return diag.Diagnostics{}
+}
+
+func resourceTemplateFromVMDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	template, err := client.GetTemplate(d.Id())
+	if err == nil {
+		if err := template.Delete(client); err != nil {
+			return diag.FromErr(err)
+		}
+	} else if !strings.Contains(err.Error(), "\"error\": 404") {
+		return diag.FromErr(err)
+	}
+	for i := 0; i < d.Get("disk.#").(int); i++ {
+		prefix := fmt.Sprintf("disk.%d.", i)
+		storage, err := client.GetStoragePool(d.Get(prefix + "storage_id").(string))
+		if err != nil && strings.Contains(err.Error(), "\"error\": 404") {
+			continue
+		} else if err != nil {
+			return diag.FromErr(err)
+		}
+		err = storage.DeleteFile(client, d.Get(prefix+"filename").(string))
+		if err != nil && !strings.Contains(err.Error(), "\"error\": 404") {
+			return diag.FromErr(err)
+		}
+	}
+	return diag.Diagnostics{}
+}
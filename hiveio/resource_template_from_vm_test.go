@@ -0,0 +1,75 @@
+package hiveio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func TestTemplateFromVMCreate(t *testing.T) {
+	tests := []struct {
+		name          string
+		failCopies    map[string]string
+		templateState string
+		wantFiles     []string
+		wantTemplate  bool
+	}{
+		{
+			name:         "copies the disks and registers the template",
+			wantFiles:    []string{"disks/golden-disk0.qcow2", "disks/golden-disk1.qcow2"},
+			wantTemplate: true,
+		},
+		{
+			name:       "removes the copied disks when a copy fails",
+			failCopies: map[string]string{"golden-disk1.qcow2": "out of space"},
+		},
+		{
+			name:          "removes the template and disks when the template is not available",
+			templateState: "failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hive, client := newFakeHive(t)
+			hive.failCopies = tt.failCopies
+			hive.templateState = tt.templateState
+			pool := testVMPool()
+			pool.GuestProfile.Disks = append(pool.GuestProfile.Disks,
+				&rest.PoolDisk{Type: "CD-ROM", StorageID: "isos", Filename: "ubuntu.iso"},
+				&rest.PoolDisk{Type: "Disk", StorageID: "disks", Filename: "data.qcow2"},
+			)
+			hive.pools[testVMID] = pool
+
+			r := resourceTemplateFromVM()
+			d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+				"name":        "golden",
+				"vm_id":       testVMID,
+				"shutdown_vm": false,
+			})
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			diags := r.CreateContext(ctx, d, client)
+			if tt.wantTemplate == diags.HasError() {
+				t.Fatalf("expected success %t, got %v", tt.wantTemplate, diags)
+			}
+
+			for _, file := range tt.wantFiles {
+				if !hive.files[file] {
+					t.Errorf("expected %s to exist", file)
+				}
+			}
+			if len(hive.files) != len(tt.wantFiles) {
+				t.Errorf("expected files %v, got %v", tt.wantFiles, hive.files)
+			}
+			if _, ok := hive.templates["golden"]; ok != tt.wantTemplate {
+				t.Errorf("expected template %t, got %t", tt.wantTemplate, ok)
+			}
+			if tt.wantTemplate && d.Id() != "golden" {
+				t.Errorf("expected id golden, got %q", d.Id())
+			}
+		})
+	}
+}
@@ -2,11 +2,16 @@ package hiveio
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hive-io/hive-go-client/rest"
@@ -18,11 +23,26 @@ func resourceVM() *schema.Resource {
 		ReadContext:   resourceVMRead,
 		UpdateContext: resourceVMUpdate,
 		DeleteContext: resourceVMDelete,
+		CustomizeDiff: customdiff.All(
+			validateBootOrder,
+			validateCloudInit,
+			validateReadyMethodSettings,
+			validateGuestResources,
+			validateHostDevices,
+			validateCloneSource,
+			diffVMDefaults,
+			validateSecureBoot,
+			diffCdromEject,
+			customdiff.ComputedIf("host_id", func(ctx context.Context, d *schema.ResourceDiff, m interface{}) bool {
+				return d.HasChange("allowed_hosts")
+			}),
+		),
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
+			StateContext: importPool("standalone"),
 		},
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(10 * time.Minute),
+			Update: schema.DefaultTimeout(10 * time.Minute),
 			Delete: schema.DefaultTimeout(10 * time.Minute),
 		},
 
@@ -31,32 +51,52 @@ func resourceVM() *schema.Resource {
 				Type:     schema.TypeString,
 				Required: true,
 			},
+			"description": descriptionSchema(),
+			"labels":      labelsSchema(),
 			"cpu": {
 				Type:     schema.TypeInt,
-				Required: true,
+				Optional: true,
+				Computed: true,
 			},
 			"memory": {
 				Type:     schema.TypeInt,
-				Required: true,
+				Optional: true,
+				Computed: true,
 			},
+			"cpu_min":    rangeBoundSchema("The minimum number of vcpus. Defaults to cpu."),
+			"cpu_max":    rangeBoundSchema("The maximum number of vcpus. Defaults to cpu."),
+			"memory_min": rangeBoundSchema("The minimum memory in MB when memory ballooning is used. Defaults to memory."),
+			"memory_max": rangeBoundSchema("The maximum memory in MB when memory ballooning is used. Defaults to memory."),
 			"gpu": {
 				Type:     schema.TypeBool,
 				Default:  false,
 				Optional: true,
 			},
+			"gpu_profile": gpuProfileSchema(),
+			"pci_device":  pciDeviceSchema(),
+			"usb_device":  usbDeviceSchema(),
 			"firmware": {
-				Type:     schema.TypeString,
-				Default:  "uefi",
-				Optional: true,
+				Type:        schema.TypeString,
+				Description: "uefi or bios. Defaults to uefi unless inherited from clone_from.",
+				Optional:    true,
+				Computed:    true,
+			},
+			"secure_boot": {
+				Type:        schema.TypeBool,
+				Description: "Enable Secure Boot. Requires uefi firmware.",
+				Default:     false,
+				Optional:    true,
 			},
 			"display_driver": {
-				Type:     schema.TypeString,
-				Default:  "cirrus",
-				Optional: true,
+				Type:        schema.TypeString,
+				Description: "Defaults to cirrus unless inherited from clone_from.",
+				Optional:    true,
+				Computed:    true,
 			},
 			"os": {
 				Type:     schema.TypeString,
-				Required: true,
+				Optional: true,
+				Computed: true,
 			},
 			"inject_agent": {
 				Type:     schema.TypeBool,
@@ -66,14 +106,16 @@ func resourceVM() *schema.Resource {
 			"disk": {
 				Type:     schema.TypeList,
 				Optional: true,
+				Computed: true,
 				ForceNew: true,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
 						"type": {
-							Type:     schema.TypeString,
-							Default:  "Disk",
-							Optional: true,
-							ForceNew: true,
+							Type:         schema.TypeString,
+							Default:      "Disk",
+							Optional:     true,
+							ForceNew:     true,
+							ValidateFunc: validateDiskType,
 						},
 						"storage_id": {
 							Type:     schema.TypeString,
@@ -101,9 +143,53 @@ func resourceVM() *schema.Resource {
 					},
 				},
 			},
+			"cdrom": {
+				Type:        schema.TypeList,
+				Description: "An iso image from a storage pool to attach as a cdrom.",
+				Optional:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"storage_id": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"filename": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"disk_driver": {
+							Type:     schema.TypeString,
+							Default:  "sata",
+							Optional: true,
+						},
+						"eject_after_first_boot": {
+							Type:        schema.TypeBool,
+							Description: "Remove the media from the vm once the guest has shut down after its first boot, for example at the end of an install. The media is removed by the next apply after the shutdown.",
+							Default:     false,
+							Optional:    true,
+						},
+					},
+				},
+			},
+			"first_boot_complete": {
+				Type:        schema.TypeBool,
+				Description: "Whether the guest has shut down after its first boot.",
+				Computed:    true,
+			},
+			"ejected_cdroms": {
+				Type:        schema.TypeList,
+				Description: "The cdroms that have been ejected after the first boot as storage_id/filename.",
+				Computed:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"boot_order": &bootOrderSchema,
+			"clone_from": cloneFromSchema(),
 			"interface": {
 				Type:     schema.TypeList,
 				Optional: true,
+				Computed: true,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
 						"network": {
@@ -166,21 +252,24 @@ func resourceVM() *schema.Resource {
 				Default:  "",
 				Optional: true,
 			},
+			"cloudinit": cloudInitSchema(),
 			"allowed_hosts": {
-				Type:     schema.TypeList,
-				Optional: true,
+				Type:        schema.TypeList,
+				Description: "The hosts the vm may run on. A running guest on a host that is no longer allowed is live migrated to the available allowed host with the most free memory that has enough threads and memory for it. A guest that is off is started on an allowed host by Hive, a guest that is starting or stopping fails the apply. Migration does not take hiveio_placement_group policies into account.",
+				Optional:    true,
 				Elem: &schema.Schema{
 					Type: schema.TypeString,
 				},
 			},
 			"broker_default_connection": {
 				Type:     schema.TypeString,
-				Default:  "",
 				Optional: true,
+				Computed: true,
 			},
 			"broker_connection": {
 				Type:     schema.TypeList,
 				Optional: true,
+				Computed: true,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
 						"name": {
@@ -241,14 +330,19 @@ func resourceVM() *schema.Resource {
 				Optional:    true,
 			},
 			"wait_for_ready_method": {
-				Type:        schema.TypeString,
-				Default:     "targetState",
-				Description: "Wait for the VM to reach a specific state. Allowed values are 'targetState', 'ready', and 'ipAddress'.",
+				Type:         schema.TypeString,
+				Default:      "targetState",
+				Description:  "Wait for the VM to reach a specific state. Allowed values are 'targetState', 'ready', 'ipAddress', 'tcpPort', 'guestAgent', and 'brokerConnection'. 'tcpPort' waits for `wait_for_ready_port` to accept connections on the guest ip, 'guestAgent' waits for the injected guest agent to report in, and 'brokerConnection' waits for the port of the default broker connection. The same check runs after updates that restart the guest, and all waits of an apply share its timeout. 'cloudInit' is a deprecated name for 'guestAgent': Hive does not report when cloud-init finishes, so a cloud-init run that starts the agent before its last step is not waited for.",
+				Optional:     true,
+				ValidateFunc: validateReadyMethod,
+			},
+			"wait_for_ready_port": {
+				Type:        schema.TypeInt,
+				Description: "The tcp port to check when wait_for_ready_method is 'tcpPort'.",
 				Optional:    true,
 				ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
-					v := val.(string)
-					if v != "targetState" && v != "ready" && v != "ipAddress" {
-						errs = append(errs, fmt.Errorf("%q must be targetState, ready, or ipAddress", key))
+					if v := val.(int); v < 1 || v > 65535 {
+						errs = append(errs, fmt.Errorf("%q must be a port between 1 and 65535", key))
 					}
 					return
 				},
@@ -258,40 +352,75 @@ func resourceVM() *schema.Resource {
 				Description: "The name of the vm from the guest record",
 				Computed:    true,
 			},
+			"tpm": {
+				Type:        schema.TypeBool,
+				Description: "Whether the guest has a virtual TPM. The TPM is provided by the host and its state is kept across updates.",
+				Computed:    true,
+			},
+			"host_id": {
+				Type:        schema.TypeString,
+				Description: "The id of the host the guest is running on",
+				Computed:    true,
+			},
+			"deletion_protection": deletionProtectionSchema(),
+			"retain_disks_on_destroy": {
+				Type:        schema.TypeBool,
+				Description: "Detach the disks before the vm is destroyed so the disk files are kept. The kept files are listed in retained_disks.",
+				Default:     false,
+				Optional:    true,
+			},
+			"retained_disks":    retainedDisksSchema(),
 			"provider_override": &providerOverride,
 		},
 	}
 
 }
 
-func vmFromResource(d *schema.ResourceData) *rest.Pool {
+func vmFromResource(d *schema.ResourceData) (*rest.Pool, error) {
 	pool := rest.Pool{
 		Name:        d.Get("name").(string),
 		InjectAgent: d.Get("inject_agent").(bool),
 		Type:        "standalone",
 		Density:     []int{1, 1},
+		Description: d.Get("description").(string),
+		Tags:        labelTags(expandLabels(d.Get("labels"))),
 	}
 
+	firmware := d.Get("firmware").(string)
+	if firmware == "" {
+		firmware = "uefi"
+	}
+	displayDriver := d.Get("display_driver").(string)
+	if displayDriver == "" {
+		displayDriver = "cirrus"
+	}
 	guestProfile := rest.PoolGuestProfile{
 		OS:         d.Get("os").(string),
-		Firmware:   d.Get("firmware").(string),
-		Vga:        d.Get("display_driver").(string),
+		Firmware:   firmware,
+		Secureboot: d.Get("secure_boot").(bool),
+		Vga:        displayDriver,
 		Gpu:        d.Get("gpu").(bool),
 		Persistent: true,
 	}
 
-	if cpu, ok := d.GetOk("cpu"); ok {
-		guestProfile.CPU = []int{cpu.(int), cpu.(int)}
-	}
-	if mem, ok := d.GetOk("memory"); ok {
-		guestProfile.Mem = []int{mem.(int), mem.(int)}
-	}
+	guestProfile.CPU = guestProfileRange(d, "cpu", "cpu_min", "cpu_max")
+	guestProfile.Mem = guestProfileRange(d, "memory", "memory_min", "memory_max")
 	if cloudInitEnabled := d.Get("cloudinit_enabled").(bool); cloudInitEnabled {
 		cloudInit := rest.PoolCloudInit{
 			Enabled:       cloudInitEnabled,
 			UserData:      d.Get("cloudinit_userdata").(string),
 			NetworkConfig: d.Get("cloudinit_networkconfig").(string),
 		}
+		userData, networkConfig, err := renderCloudInit(d.Get("cloudinit").([]interface{}))
+		if err != nil {
+			return nil, err
+		}
+		if userData != "" {
+			cloudInit.UserData = userData
+		}
+		if networkConfig != "" {
+			cloudInit.NetworkConfig = networkConfig
+		}
 		guestProfile.CloudInit = &cloudInit
 	}
 	pool.GuestProfile = &guestProfile
@@ -300,10 +429,12 @@ func vmFromResource(d *schema.ResourceData) *rest.Pool {
 		pool.ID = d.Id()
 	}
 
+	bootOrder := bootPositions(d)
 	var disks []*rest.PoolDisk
 	for i := 0; i < d.Get("disk.#").(int); i++ {
 		prefix := fmt.Sprintf("disk.%d.", i)
 		disk := rest.PoolDisk{
+			BootOrder:  bootOrder[fmt.Sprintf("disk.%d", i)],
 			DiskDriver: d.Get(prefix + "disk_driver").(string),
 			Type:       d.Get(prefix + "type").(string),
 			StorageID:  d.Get(prefix + "storage_id").(string),
@@ -311,6 +442,21 @@ func vmFromResource(d *schema.ResourceData) *rest.Pool {
 		}
 		disks = append(disks, &disk)
 	}
+	ejected := ejectedCdroms(d)
+	for i := 0; i < d.Get("cdrom.#").(int); i++ {
+		prefix := fmt.Sprintf("cdrom.%d.", i)
+		if ejected[cdromPath(d.Get(prefix+"storage_id").(string), d.Get(prefix+"filename").(string))] {
+			continue
+		}
+		cdrom := rest.PoolDisk{
+			BootOrder:  bootOrder[fmt.Sprintf("cdrom.%d", i)],
+			DiskDriver: d.Get(prefix + "disk_driver").(string),
+			Type:       cdromDiskType,
+			StorageID:  d.Get(prefix + "storage_id").(string),
+			Filename:   d.Get(prefix + "filename").(string),
+		}
+		disks = append(disks, &cdrom)
+	}
 	pool.GuestProfile.Disks = disks
 
 	var interfaces []*rest.PoolInterface
@@ -344,6 +490,7 @@ func vmFromResource(d *schema.ResourceData) *rest.Pool {
 		pool.PoolAffinity.AllowedHostIDs = []string{}
 	}
 	if nConnections, ok := d.Get("broker_connection.#").(int); ok && nConnections > 0 {
+		pool.GuestProfile.BrokerOptions = &rest.GuestBrokerOptions{}
 		pool.GuestProfile.BrokerOptions.DefaultConnection = d.Get("broker_default_connection").(string)
 		var connections []rest.GuestBrokerConnection
 		for i := 0; i < nConnections; i++ {
@@ -369,7 +516,56 @@ func vmFromResource(d *schema.ResourceData) *rest.Pool {
 		pool.GuestProfile.BrokerOptions.Connections = connections
 	}
 
-	return &pool
+	return &pool, nil
+}
+
+// brokerConnections converts broker options to broker_connection blocks
+func brokerConnections(options *rest.GuestBrokerOptions) []interface{} {
+	connection := make([]interface{}, len(options.Connections))
+	for i, conn := range options.Connections {
+		connection[i] = map[string]interface{}{
+			"name":          conn.Name,
+			"description":   conn.Description,
+			"port":          conn.Port,
+			"protocol":      conn.Protocol,
+			"disable_html5": conn.DisableHtml5,
+			"gateway": []interface{}{
+				map[string]interface{}{
+					"disabled":   conn.Gateway.Disabled,
+					"persistent": conn.Gateway.Persistent,
+					"protocols":  conn.Gateway.Protocols,
+				},
+			},
+		}
+	}
+	return connection
+}
+
+var errGuestNotFound = errors.New("guest not found")
+
+// getPoolGuest finds the guest of a standalone pool from the guests that belong to it
+func getPoolGuest(client *rest.Client, poolID string) (*rest.Guest, error) {
+	guests, err := client.ListGuests("poolId=" + url.QueryEscape(poolID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list guests for pool %s: %w", poolID, err)
+	}
+	var members []rest.Guest
+	for _, guest := range guests {
+		if guest.PoolID == poolID {
+			members = append(members, guest)
+		}
+	}
+	switch len(members) {
+	case 0:
+		return nil, fmt.Errorf("%w for pool %s", errGuestNotFound, poolID)
+	case 1:
+		return &members[0], nil
+	}
+	names := make([]string, len(members))
+	for i, guest := range members {
+		names[i] = guest.Name
+	}
+	return nil, fmt.Errorf("expected one guest for pool %s but found %d: %s", poolID, len(members), strings.Join(names, ", "))
 }
 
 func resourceVMCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
@@ -377,44 +573,28 @@ func resourceVMCreate(ctx context.Context, d *schema.ResourceData, m interface{}
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	pool := vmFromResource(d)
-
-	_, err = pool.Create(client)
+	cloned, err := applyCloneSource(ctx, client, d)
 	if err != nil {
 		return diag.FromErr(err)
 	}
+	pool, err := vmFromResource(d)
+	if err == nil {
+		err = applyHostDevices(client, d, pool)
+	}
+	if err == nil {
+		_, err = pool.Create(client)
+	}
+	if err != nil {
+		// the vm was not created so nothing else uses the cloned disks
+		return diag.FromErr(errors.Join(err, deleteClonedDisks(client, cloned)))
+	}
 	pool, err = client.GetPoolByName(pool.Name)
 	if err != nil {
 		return diag.FromErr(err)
 	}
 
 	if d.Get("wait_for_ready").(bool) {
-		guestName := strings.ToUpper(pool.Name)
-		guestName = strings.ReplaceAll(guestName, " ", "_")
-		err = retry.RetryContext(ctx, d.Timeout(schema.TimeoutCreate), func() *retry.RetryError {
-			guest, err := client.GetGuest(guestName)
-			if err != nil {
-				if strings.Contains(err.Error(), "\"error\": 404") {
-					time.Sleep(5 * time.Second)
-					return retry.RetryableError(fmt.Errorf("building pool %s", pool.ID))
-				}
-				return retry.NonRetryableError(err)
-			}
-			method := d.Get("wait_for_ready_method").(string)
-			switch method {
-			case "targetState":
-				err = guest.WaitForGuestWithContext(ctx, client, d.Timeout(schema.TimeoutCreate))
-			case "ready":
-				err = guest.WaitForGuestChange(ctx, client, d.Timeout(schema.TimeoutCreate), rest.IsGuestReady)
-			case "ipAddress":
-				err = guest.WaitForGuestChange(ctx, client, d.Timeout(schema.TimeoutCreate), rest.GuestHasIpAddress)
-			}
-			if err != nil {
-				return retry.NonRetryableError(err)
-			}
-			return nil
-		})
-		if err != nil {
+		if err := waitForVMReady(ctx, client, d, pool, remainingTimeout(ctx, d.Timeout(schema.TimeoutCreate))); err != nil {
 			return diag.FromErr(err)
 		}
 	}
@@ -422,6 +602,57 @@ func resourceVMCreate(ctx context.Context, d *schema.ResourceData, m interface{}
 	return resourceVMRead(ctx, d, m)
 }
 
+// cdromPath identifies a cdrom by its media so ejected cdroms stay ejected when blocks are reordered
+func cdromPath(storageID, filename string) string {
+	return storageID + "/" + filename
+}
+
+// ejectedCdroms returns the paths of the cdroms that have been ejected
+func ejectedCdroms(d resourceGetter) map[string]bool {
+	ejected := make(map[string]bool)
+	for _, path := range d.Get("ejected_cdroms").([]interface{}) {
+		ejected[path.(string)] = true
+	}
+	return ejected
+}
+
+// diffCdromEject plans the removal of cdroms marked eject_after_first_boot once the guest has shut
+// down after its first boot. Read records the shutdown so the media is never removed by the apply
+// that boots the guest.
+func diffCdromEject(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if d.Id() == "" || !d.Get("first_boot_complete").(bool) {
+		return nil
+	}
+	ejected := ejectedCdroms(d)
+	paths := []string{}
+	for i := 0; i < d.Get("cdrom.#").(int); i++ {
+		prefix := fmt.Sprintf("cdrom.%d.", i)
+		path := cdromPath(d.Get(prefix+"storage_id").(string), d.Get(prefix+"filename").(string))
+		if (ejected[path] || d.Get(prefix+"eject_after_first_boot").(bool)) && !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	var current []string
+	for _, path := range d.Get("ejected_cdroms").([]interface{}) {
+		current = append(current, path.(string))
+	}
+	if slices.Equal(current, paths) {
+		return nil
+	}
+	return d.SetNew("ejected_cdroms", paths)
+}
+
+// vlanID converts the vlan of a pool interface, which is a json number or missing
+func vlanID(vlan interface{}) int {
+	switch v := vlan.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
 func resourceVMRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	client, err := getClient(d, m)
 	if err != nil {
@@ -434,62 +665,175 @@ func resourceVMRead(ctx context.Context, d *schema.ResourceData, m interface{})
 	} else if err != nil {
 		return diag.FromErr(err)
 	}
-	guestName := strings.ToUpper(pool.Name)
-	guestName = strings.ReplaceAll(guestName, " ", "_")
-	guestRecord, _ := client.GetGuest(guestName)
+	var diags diag.Diagnostics
+	guestRecord, err := getPoolGuest(client, pool.ID)
+	if errors.Is(err, errGuestNotFound) {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Guest not found",
+			Detail:   fmt.Sprintf("No guest is running for %s yet so guest_name and interface addresses are not available.", pool.Name),
+		})
+	} else if err != nil {
+		return diag.FromErr(err)
+	}
 
 	d.Set("name", pool.Name)
-	d.Set("cpu", pool.GuestProfile.CPU[0])
-	d.Set("memory", pool.GuestProfile.Mem[0])
+	d.Set("description", pool.Description)
+	labels, _ := tagLabels(pool.Tags)
+	d.Set("labels", flattenLabels(labels))
+	setGuestProfileRange(d, "cpu", "cpu_min", "cpu_max", pool.GuestProfile.CPU)
+	setGuestProfileRange(d, "memory", "memory_min", "memory_max", pool.GuestProfile.Mem)
 	d.Set("gpu", pool.GuestProfile.Gpu)
 	d.Set("inject_agent", pool.InjectAgent)
 	d.Set("os", pool.GuestProfile.OS)
 	d.Set("firmware", pool.GuestProfile.Firmware)
+	d.Set("secure_boot", pool.GuestProfile.Secureboot)
 	d.Set("display_driver", pool.GuestProfile.Vga)
 
-	disks := make([]interface{}, len(pool.GuestProfile.Disks))
+	disks := []interface{}{}
+	var poolCdroms []*rest.PoolDisk
+	diskKeys := make([]string, len(pool.GuestProfile.Disks))
 	for i, disk := range pool.GuestProfile.Disks {
-		disks[i] = map[string]interface{}{
+		if isCdrom(disk.Type) {
+			poolCdroms = append(poolCdroms, disk)
+			continue
+		}
+		diskKeys[i] = fmt.Sprintf("disk.%d", len(disks))
+		var size, dev string
+		if guestRecord != nil {
+			for _, guestDisk := range guestRecord.Disks {
+				if guestDisk.StorageID == disk.StorageID && guestDisk.Filename == disk.Filename {
+					size = strconv.Itoa(guestDisk.Size)
+					dev = guestDisk.Device
+					break
+				}
+			}
+		}
+		disks = append(disks, map[string]interface{}{
 			"type":        disk.Type,
 			"storage_id":  disk.StorageID,
 			"filename":    disk.Filename,
 			"disk_driver": disk.DiskDriver,
-		}
+			"size":        size,
+			"dev":         dev,
+		})
 	}
 	d.Set("disk", disks)
 
-	interfaces := make([]interface{}, len(pool.GuestProfile.Interfaces))
-	if guestRecord != nil && len(guestRecord.Interfaces) > 0 {
-		for i, iface := range guestRecord.Interfaces {
-			interfaces[i] = map[string]interface{}{
-				"network":     iface.Network,
-				"vlan":        iface.Vlan,
-				"emulation":   iface.Emulation,
-				"ip_address":  iface.IPAddress,
-				"mac_address": iface.MacAddress,
+	// cdroms ejected after the first boot are no longer in the pool so they are kept from state
+	cdroms := []interface{}{}
+	ejectedPaths := ejectedCdroms(d)
+	ejected := make(map[string]bool)
+	ejectedList := []string{}
+	matched := make(map[*rest.PoolDisk]string)
+	for _, v := range d.Get("cdrom").([]interface{}) {
+		cdrom := v.(map[string]interface{})
+		key := fmt.Sprintf("cdrom.%d", len(cdroms))
+		if path := cdromPath(cdrom["storage_id"].(string), cdrom["filename"].(string)); ejectedPaths[path] {
+			ejected[key] = true
+			if !slices.Contains(ejectedList, path) {
+				ejectedList = append(ejectedList, path)
+			}
+			cdroms = append(cdroms, cdrom)
+			continue
+		}
+		for _, disk := range poolCdroms {
+			if _, ok := matched[disk]; !ok && disk.StorageID == cdrom["storage_id"] && disk.Filename == cdrom["filename"] {
+				matched[disk] = key
+				cdrom["disk_driver"] = disk.DiskDriver
+				cdroms = append(cdroms, cdrom)
+				break
 			}
+		}
+	}
+	for _, disk := range poolCdroms {
+		if _, ok := matched[disk]; !ok {
+			matched[disk] = fmt.Sprintf("cdrom.%d", len(cdroms))
+			cdroms = append(cdroms, map[string]interface{}{
+				"storage_id":             disk.StorageID,
+				"filename":               disk.Filename,
+				"disk_driver":            disk.DiskDriver,
+				"eject_after_first_boot": false,
+			})
+		}
+	}
+	d.Set("cdrom", cdroms)
+	d.Set("ejected_cdroms", ejectedList)
 
+	for i, disk := range pool.GuestProfile.Disks {
+		if key, ok := matched[disk]; ok {
+			diskKeys[i] = key
 		}
-		if err := d.Set("interface", interfaces); err != nil {
-			return diag.FromErr(err)
+	}
+	bootOrder := poolBootOrder(pool.GuestProfile.Disks, diskKeys)
+	var stateBootOrder []string
+	for _, v := range d.Get("boot_order").([]interface{}) {
+		if !ejected[v.(string)] {
+			stateBootOrder = append(stateBootOrder, v.(string))
 		}
-		if err := d.Set("guest_name", guestRecord.Name); err != nil {
-			return diag.FromErr(err)
+	}
+	if len(ejected) > 0 && strings.Join(stateBootOrder, ",") == strings.Join(bootOrder, ",") {
+		bootOrder = nil
+		for _, v := range d.Get("boot_order").([]interface{}) {
+			bootOrder = append(bootOrder, v.(string))
+		}
+	}
+	d.Set("boot_order", bootOrder)
+
+	// the pool holds the configured interfaces, the guest record adds the addresses
+	var guestInterfaces []rest.GuestNetwork
+	if guestRecord != nil {
+		guestInterfaces = guestRecord.Interfaces
+		// a guest that was ready once and is now off has finished its first boot
+		if guestRecord.HasBeenReady && guestStopped(*guestRecord) {
+			d.Set("first_boot_complete", true)
 		}
+		d.Set("guest_name", guestRecord.Name)
+		d.Set("host_id", guestRecord.Hostid)
+		d.Set("tpm", guestRecord.Tpm)
 	} else {
-		for i, iface := range pool.GuestProfile.Interfaces {
-			interfaces[i] = map[string]interface{}{
-				"network":   iface.Network,
-				"vlan":      iface.Vlan,
-				"emulation": iface.Emulation,
-			}
+		d.Set("guest_name", "")
+		d.Set("host_id", "")
+		d.Set("tpm", false)
+	}
+	interfaces := make([]interface{}, len(pool.GuestProfile.Interfaces))
+	for i, iface := range pool.GuestProfile.Interfaces {
+		var ipAddress, macAddress string
+		if i < len(guestInterfaces) {
+			ipAddress = guestInterfaces[i].IPAddress
+			macAddress = guestInterfaces[i].MacAddress
+		}
+		interfaces[i] = map[string]interface{}{
+			"network":     iface.Network,
+			"vlan":        vlanID(iface.Vlan),
+			"emulation":   iface.Emulation,
+			"ip_address":  ipAddress,
+			"mac_address": macAddress,
 		}
 	}
+	if err := d.Set("interface", interfaces); err != nil {
+		return diag.FromErr(err)
+	}
 
 	if pool.GuestProfile.CloudInit != nil {
 		d.Set("cloudinit_enabled", pool.GuestProfile.CloudInit.Enabled)
-		d.Set("cloudinit_userdata", pool.GuestProfile.CloudInit.UserData)
-		d.Set("cloudinit_networkconfig", pool.GuestProfile.CloudInit.NetworkConfig)
+		userData := pool.GuestProfile.CloudInit.UserData
+		networkConfig := pool.GuestProfile.CloudInit.NetworkConfig
+		// settings rendered from the cloudinit block are not stored in the raw attributes
+		// unless they no longer match, which shows the drift in the plan
+		renderedUserData, renderedNetworkConfig, err := renderCloudInit(d.Get("cloudinit").([]interface{}))
+		if err == nil && renderedUserData != "" && renderedUserData == userData {
+			userData = ""
+		}
+		if err == nil && renderedNetworkConfig != "" && renderedNetworkConfig == networkConfig {
+			networkConfig = ""
+		}
+		d.Set("cloudinit_userdata", userData)
+		d.Set("cloudinit_networkconfig", networkConfig)
+	} else {
+		d.Set("cloudinit_enabled", false)
+		d.Set("cloudinit_userdata", "")
+		d.Set("cloudinit_networkconfig", "")
 	}
 
 	if pool.Backup != nil {
@@ -500,35 +844,47 @@ func resourceVMRead(ctx context.Context, d *schema.ResourceData, m interface{})
 				"target":    pool.Backup.TargetStorageID,
 			},
 		})
+	} else {
+		d.Set("backup", nil)
 	}
 
-	if pool.PoolAffinity != nil && len(pool.PoolAffinity.AllowedHostIDs) > 0 {
-		d.Set("allowed_hosts", pool.PoolAffinity.AllowedHostIDs)
+	if d.Get("retain_disks_on_destroy").(bool) {
+		d.Set("retained_disks", vmRetainedDisks(pool))
+	} else {
+		d.Set("retained_disks", nil)
+	}
+
+	d.Set("gpu_profile", poolGpuProfile(pool.GuestProfile.HostDevices))
+	pciDevices, usbDevices, err := readHostDevices(client, d, pool)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	d.Set("pci_device", pciDevices)
+	d.Set("usb_device", usbDevices)
+	// allowed hosts narrowed down to the hosts with the requested devices stay as configured
+	if !hostDevicesRequested(d) {
+		var allowedHosts []string
+		if pool.PoolAffinity != nil {
+			allowedHosts = pool.PoolAffinity.AllowedHostIDs
+		}
+		d.Set("allowed_hosts", allowedHosts)
 	}
 
 	if pool.GuestProfile.BrokerOptions != nil {
 		d.Set("broker_default_connection", pool.GuestProfile.BrokerOptions.DefaultConnection)
-		connection := make([]interface{}, len(pool.GuestProfile.BrokerOptions.Connections))
-		for i, conn := range pool.GuestProfile.BrokerOptions.Connections {
-			connection[i] = map[string]interface{}{
-				"name":          conn.Name,
-				"description":   conn.Description,
-				"port":          conn.Port,
-				"protocol":      conn.Protocol,
-				"disable_html5": conn.DisableHtml5,
-				"gateway": []interface{}{
-					map[string]interface{}{
-						"disabled":   conn.Gateway.Disabled,
-						"persistent": conn.Gateway.Persistent,
-						"protocols":  conn.Gateway.Protocols,
-					},
-				},
-			}
-		}
-		d.Set("broker_connection", connection)
+		d.Set("broker_connection", brokerConnections(pool.GuestProfile.BrokerOptions))
+	} else {
+		d.Set("broker_default_connection", "")
+		d.Set("broker_connection", nil)
 	}
 
-	return diag.Diagnostics{}
+	// the wait settings only exist in terraform, imported vms get the defaults
+	if _, ok := d.GetOk("wait_for_ready_method"); !ok {
+		d.Set("wait_for_ready", true)
+		d.Set("wait_for_ready_method", "targetState")
+	}
+
+	return diags
 }
 
 func resourceVMUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
@@ -536,11 +892,53 @@ func resourceVMUpdate(ctx context.Context, d *schema.ResourceData, m interface{}
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	pool := vmFromResource(d)
+	pool, err := vmFromResource(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	// tags set outside terraform are kept, only the label tags are managed here
+	current, err := client.GetPool(d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	pool.Tags = keepUnmanagedTags(current.Tags, pool.Tags, labelTagPrefix)
+	if err := applyHostDevices(client, d, pool); err != nil {
+		return diag.FromErr(err)
+	}
+	waitForRestart := d.Get("wait_for_ready").(bool) && d.HasChanges(vmRestartAttributes...)
+	var previous *rest.Guest
+	if waitForRestart {
+		previous, err = getPoolGuest(client, pool.ID)
+		if err != nil && !errors.Is(err, errGuestNotFound) {
+			return diag.FromErr(err)
+		}
+	}
 	_, err = pool.Update(client)
 	if err != nil {
 		return diag.FromErr(err)
 	}
+	if d.HasChange("allowed_hosts") {
+		guest, err := getPoolGuest(client, pool.ID)
+		if err != nil && !errors.Is(err, errGuestNotFound) {
+			return diag.FromErr(err)
+		}
+		if guest != nil {
+			err = migrateToAllowedHost(ctx, client, guest, pool.PoolAffinity.AllowedHostIDs, d.Timeout(schema.TimeoutUpdate))
+			if err != nil {
+				return diag.FromErr(err)
+			}
+		}
+	}
+	if waitForRestart {
+		if previous != nil && rest.IsGuestReady(*previous) {
+			if err := waitForGuestRestart(ctx, client, previous, remainingTimeout(ctx, d.Timeout(schema.TimeoutUpdate))); err != nil {
+				return diag.FromErr(err)
+			}
+		}
+		if err := waitForVMReady(ctx, client, d, pool, remainingTimeout(ctx, d.Timeout(schema.TimeoutUpdate))); err != nil {
+			return diag.FromErr(err)
+		}
+	}
 	return resourceVMRead(ctx, d, m)
 }
 
@@ -549,10 +947,18 @@ func resourceVMDelete(ctx context.Context, d *schema.ResourceData, m interface{}
 	if err != nil {
 		return diag.FromErr(err)
 	}
+	if err := checkDeletionProtection(d); err != nil {
+		return diag.FromErr(err)
+	}
 	pool, err := client.GetPool(d.Id())
 	if err != nil {
 		return diag.FromErr(err)
 	}
+	if d.Get("retain_disks_on_destroy").(bool) {
+		if err := detachVMDisks(client, pool); err != nil {
+			return diag.FromErr(err)
+		}
+	}
 	err = pool.Delete(client)
 	if err != nil {
 		return diag.FromErr(err)
@@ -0,0 +1,167 @@
+package hiveio
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+const testVMID = "3f6c1c4e-4a9b-4f3e-9a51-0c7ad3d6b7a2"
+
+func testVMPool() rest.Pool {
+	return rest.Pool{
+		ID:          testVMID,
+		Name:        "kubuntu",
+		Type:        "standalone",
+		Density:     []int{1, 1},
+		InjectAgent: true,
+		Tags:        []string{"label:team=desktop", "backup-policy"},
+		GuestProfile: &rest.PoolGuestProfile{
+			CPU:        []int{2, 2},
+			Mem:        []int{4096, 4096},
+			OS:         "linux",
+			Firmware:   "uefi",
+			Vga:        "cirrus",
+			Persistent: true,
+			Disks: []*rest.PoolDisk{
+				{Type: "Disk", StorageID: "disks", Filename: "kubuntu.qcow2", DiskDriver: "virtio", BootOrder: 1},
+			},
+			Interfaces: []*rest.PoolInterface{
+				{Network: "prod", Vlan: float64(0), Emulation: "virtio"},
+			},
+		},
+		PoolAffinity: &rest.PoolAffinity{AllowedHostIDs: []string{}},
+	}
+}
+
+func testVMGuest() rest.Guest {
+	return rest.Guest{
+		Name:       "KUBUNTU",
+		PoolID:     testVMID,
+		GuestState: "ready",
+		Hostid:     "host1",
+		Disks: []rest.GuestDisk{
+			{Type: "Disk", StorageID: "disks", Filename: "kubuntu.qcow2", Size: 20, Device: "vda"},
+		},
+		Interfaces: []rest.GuestNetwork{
+			{Network: "prod", IPAddress: "10.0.0.5", MacAddress: "52:54:00:12:34:56"},
+		},
+	}
+}
+
+// TestVMImportPlanIsEmpty imports a vm, reads it and checks that the configuration that matches
+// it plans no changes
+func TestVMImportPlanIsEmpty(t *testing.T) {
+	hive, client := newFakeHive(t)
+	hive.pools[testVMID] = testVMPool()
+	hive.guests = []rest.Guest{testVMGuest()}
+	config := `{
+		"name": "kubuntu",
+		"cpu": 2,
+		"memory": 4096,
+		"os": "linux",
+		"labels": {"team": "desktop"},
+		"disk": [{"storage_id": "disks", "filename": "kubuntu.qcow2", "disk_driver": "virtio"}],
+		"interface": [{"network": "prod", "emulation": "virtio"}]
+	}`
+
+	for _, importID := range []string{testVMID, "name:kubuntu"} {
+		t.Run(importID, func(t *testing.T) {
+			ctx := context.Background()
+			r := resourceVM()
+			d := r.Data(nil)
+			d.SetId(importID)
+			imported, err := r.Importer.StateContext(ctx, d, client)
+			if err != nil {
+				t.Fatalf("import failed: %s", err)
+			}
+			d = imported[0]
+			if diags := r.ReadContext(ctx, d, client); diags.HasError() {
+				t.Fatalf("read failed: %v", diags)
+			}
+			if d.Id() != testVMID {
+				t.Fatalf("expected id %s, got %s", testVMID, d.Id())
+			}
+
+			diff, err := planResource(t, r, d.State(), config, client)
+			if err != nil {
+				t.Fatalf("plan failed: %s", err)
+			}
+			if diff != nil && !diff.Empty() {
+				for key, attr := range diff.Attributes {
+					t.Errorf("%s: %q => %q", key, attr.Old, attr.New)
+				}
+				t.Fatalf("expected an empty plan after import")
+			}
+		})
+	}
+}
+
+// TestEjectedCdromsFollowReorderedBlocks checks that an ejected cdrom stays ejected when the cdrom
+// blocks are reordered, because it is identified by its media instead of its index
+func TestEjectedCdromsFollowReorderedBlocks(t *testing.T) {
+	hive, client := newFakeHive(t)
+	hive.pools[testVMID] = testVMPool()
+	hive.guests = []rest.Guest{testVMGuest()}
+
+	r := resourceVM()
+	d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"name":   "kubuntu",
+		"cpu":    2,
+		"memory": 4096,
+		"os":     "linux",
+		"disk":   []interface{}{map[string]interface{}{"storage_id": "disks", "filename": "kubuntu.qcow2"}},
+		"cdrom": []interface{}{
+			map[string]interface{}{"storage_id": "isos", "filename": "install.iso", "eject_after_first_boot": true},
+			map[string]interface{}{"storage_id": "isos", "filename": "drivers.iso"},
+		},
+	})
+	d.SetId(testVMID)
+	d.Set("first_boot_complete", true)
+	d.Set("ejected_cdroms", []string{"isos/install.iso"})
+
+	config := `{
+		"name": "kubuntu",
+		"cpu": 2,
+		"memory": 4096,
+		"os": "linux",
+		"disk": [{"storage_id": "disks", "filename": "kubuntu.qcow2"}],
+		"cdrom": [
+			{"storage_id": "isos", "filename": "drivers.iso"},
+			{"storage_id": "isos", "filename": "install.iso", "eject_after_first_boot": true}
+		]
+	}`
+	diff, err := planResource(t, r, d.State(), config, client)
+	if err != nil {
+		t.Fatalf("plan failed: %s", err)
+	}
+	if diff != nil {
+		for key, attr := range diff.Attributes {
+			if strings.HasPrefix(key, "ejected_cdroms") {
+				t.Errorf("expected the ejected cdroms to be kept, %s: %q => %q", key, attr.Old, attr.New)
+			}
+		}
+	}
+
+	d.Set("cdrom", []interface{}{
+		map[string]interface{}{"storage_id": "isos", "filename": "drivers.iso", "disk_driver": "sata"},
+		map[string]interface{}{"storage_id": "isos", "filename": "install.iso", "disk_driver": "sata", "eject_after_first_boot": true},
+	})
+	pool, err := vmFromResource(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cdroms []string
+	for _, disk := range pool.GuestProfile.Disks {
+		if isCdrom(disk.Type) {
+			cdroms = append(cdroms, disk.Filename)
+		}
+	}
+	if !slices.Equal(cdroms, []string{"drivers.iso"}) {
+		t.Fatalf("expected only drivers.iso to be attached, got %v", cdroms)
+	}
+}
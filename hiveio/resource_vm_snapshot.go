@@ -0,0 +1,204 @@
+package hiveio
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func resourceVMSnapshot() *schema.Resource {
+	return &schema.Resource{
+		Description:   "Snapshots the disks of a virtual machine. Destroying the resource merges the snapshot back into the disks. Hive can only merge all snapshots of a vm at once, so a vm can have one snapshot. The Hive api only creates and merges disk snapshots, so this resource does not support reverting to a snapshot, snapshots with memory state, retention or pruning of older snapshots, or reporting the size of a snapshot. created_at is the time the provider requested the snapshot.",
+		CreateContext: resourceVMSnapshotCreate,
+		ReadContext:   resourceVMSnapshotRead,
+		UpdateContext: resourceVMSnapshotUpdate,
+		DeleteContext: resourceVMSnapshotDelete,
+
+		Schema: map[string]*schema.Schema{
+			"vm_id": {
+				Type:        schema.TypeString,
+				Description: "The id of the hiveio_virtual_machine to snapshot.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"merge_on_destroy": {
+				Type:        schema.TypeBool,
+				Description: "Merge the snapshot into the vm disks when the resource is destroyed.",
+				Default:     true,
+				Optional:    true,
+			},
+			"created_at": {
+				Type:        schema.TypeString,
+				Description: "When the snapshot was taken in RFC 3339 format.",
+				Computed:    true,
+			},
+			"disk": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"storage_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"filename": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"snapshots": {
+							Type:        schema.TypeList,
+							Description: "The qcow2 snapshots taken in the disk by this resource.",
+							Computed:    true,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+					},
+				},
+			},
+			"provider_override": &providerOverride,
+		},
+	}
+}
+
+// vmDiskSnapshots returns the qcow2 snapshots in each disk of a vm keyed by storage id and filename
+func vmDiskSnapshots(client *rest.Client, pool *rest.Pool) (map[string][]string, error) {
+	snapshots := make(map[string][]string)
+	for _, disk := range pool.GuestProfile.Disks {
+		if isCdrom(disk.Type) {
+			continue
+		}
+		storage, err := client.GetStoragePool(disk.StorageID)
+		if err != nil {
+			return nil, err
+		}
+		info, err := storage.DiskInfo(client, disk.Filename)
+		if err != nil {
+			return nil, err
+		}
+		snapshots[disk.StorageID+"/"+disk.Filename] = info.Snapshots
+	}
+	return snapshots, nil
+}
+
+func resourceVMSnapshotCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	pool, err := client.GetPool(d.Get("vm_id").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if pool.Type != "standalone" {
+		return diag.Errorf("%s is not a virtual machine", pool.Name)
+	}
+	before, err := vmDiskSnapshots(client, pool)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	// merging a snapshot merges every snapshot of the vm, so a second snapshot could not be destroyed on its own
+	for key, snapshots := range before {
+		if len(snapshots) > 0 {
+			return diag.Errorf("%s already has a snapshot in %s, a vm can only have one snapshot", pool.Name, key)
+		}
+	}
+	createdAt := time.Now().UTC()
+	err = pool.Snapshot(client)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	after, err := vmDiskSnapshots(client, pool)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	var disks []interface{}
+	for _, disk := range pool.GuestProfile.Disks {
+		key := disk.StorageID + "/" + disk.Filename
+		if snapshots := after[key]; len(snapshots) > 0 {
+			disks = append(disks, map[string]interface{}{
+				"storage_id": disk.StorageID,
+				"filename":   disk.Filename,
+				"snapshots":  snapshots,
+			})
+		}
+	}
+	if len(disks) == 0 {
+		return diag.Errorf("no disk of %s was snapshotted, Hive only snapshots the disks of running guests", pool.Name)
+	}
+	d.Set("created_at", createdAt.Format(time.RFC3339))
+	d.Set("disk", disks)
+	d.SetId(fmt.Sprintf("%s-%d", pool.ID, createdAt.Unix()))
+	return resourceVMSnapshotRead(ctx, d, m)
+}
+
+func resourceVMSnapshotRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	pool, err := client.GetPool(d.Get("vm_id").(string))
+	if err != nil && strings.Contains(err.Error(), "\"error\": 404") {
+		d.SetId("")
+		return diag.Diagnostics{}
+	} else if err != nil {
+		return diag.FromErr(err)
+	}
+	current, err := vmDiskSnapshots(client, pool)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// only the snapshots taken by this resource are tracked, once they are all merged it is gone
+	var disks []interface{}
+	for _, v := range d.Get("disk").([]interface{}) {
+		disk := v.(map[string]interface{})
+		var remaining []string
+		for _, snapshot := range disk["snapshots"].([]interface{}) {
+			if slices.Contains(current[disk["storage_id"].(string)+"/"+disk["filename"].(string)], snapshot.(string)) {
+				remaining = append(remaining, snapshot.(string))
+			}
+		}
+		if len(remaining) > 0 {
+			disk["snapshots"] = remaining
+			disks = append(disks, disk)
+		}
+	}
+	if len(disks) == 0 {
+		d.SetId("")
+		return diag.Diagnostics{}
+	}
+	d.Set("disk", disks)
+	return diag.Diagnostics{}
+}
+
+func resourceVMSnapshotUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	return resourceVMSnapshotRead(ctx, d, m)
+}
+
+func resourceVMSnapshotDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if !d.Get("merge_on_destroy").(bool) {
+		return diag.Diagnostics{}
+	}
+	client, err := getClient(d, m)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	pool, err := client.GetPool(d.Get("vm_id").(string))
+	if err != nil && strings.Contains(err.Error(), "\"error\": 404") {
+		return diag.Diagnostics{}
+	} else if err != nil {
+		return diag.FromErr(err)
+	}
+	err = pool.Merge(client)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return diag.Diagnostics{}
+}
@@ -0,0 +1,183 @@
+package hiveio
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func deletionProtectionSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeBool,
+		Description: "Refuse to destroy the pool. Set to false and apply before destroying or replacing it.",
+		Default:     false,
+		Optional:    true,
+	}
+}
+
+func retainedDisksSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Description: "The disk files kept when the pool is destroyed with retain_disks_on_destroy.",
+		Computed:    true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"storage_id": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"filename": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+			},
+		},
+	}
+}
+
+// checkDeletionProtection fails the destroy of a pool with deletion_protection set
+func checkDeletionProtection(d *schema.ResourceData) error {
+	if d.Get("deletion_protection").(bool) {
+		return fmt.Errorf("%s has deletion_protection enabled, set deletion_protection to false and apply before destroying it", d.Get("name").(string))
+	}
+	return nil
+}
+
+// vmRetainedDisks lists the disk files of a vm that are detached before it is deleted
+func vmRetainedDisks(pool *rest.Pool) []interface{} {
+	disks := []interface{}{}
+	for _, disk := range pool.GuestProfile.Disks {
+		if isCdrom(disk.Type) {
+			continue
+		}
+		disks = append(disks, map[string]interface{}{
+			"storage_id": disk.StorageID,
+			"filename":   disk.Filename,
+		})
+	}
+	return disks
+}
+
+// detachVMDisks removes the disks from a vm so they are not deleted with it
+func detachVMDisks(client *rest.Client, pool *rest.Pool) error {
+	var cdroms []*rest.PoolDisk
+	for _, disk := range pool.GuestProfile.Disks {
+		if isCdrom(disk.Type) {
+			cdroms = append(cdroms, disk)
+		}
+	}
+	if len(cdroms) == len(pool.GuestProfile.Disks) {
+		return nil
+	}
+	pool.GuestProfile.Disks = cdroms
+	if _, err := pool.Update(client); err != nil {
+		return fmt.Errorf("failed to detach disks from %s: %w", pool.Name, err)
+	}
+	return nil
+}
+
+// retainedGuestDisk is a disk of a persistent guest and the file it is copied to
+type retainedGuestDisk struct {
+	guest    *rest.Guest
+	disk     *rest.PoolDisk
+	filename string
+}
+
+// guestPoolRetainedDisks lists the disks of the persistent guests in a pool and the names they are
+// copied to before the pool is deleted. The names include the pool id so a pool created again with
+// the same guest names does not collide with the copies of an earlier pool.
+func guestPoolRetainedDisks(client *rest.Client, pool *rest.Pool) ([]retainedGuestDisk, error) {
+	if !pool.GuestProfile.Persistent {
+		return nil, nil
+	}
+	guests, err := client.ListGuests("poolId=" + url.QueryEscape(pool.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list guests for pool %s: %w", pool.Name, err)
+	}
+	var disks []retainedGuestDisk
+	for _, guest := range guests {
+		if guest.PoolID != pool.ID {
+			continue
+		}
+		guest := guest
+		i := 0
+		for _, disk := range guest.Disks {
+			if isCdrom(disk.Type) || disk.Filename == "" {
+				continue
+			}
+			disks = append(disks, retainedGuestDisk{
+				guest: &guest,
+				disk: &rest.PoolDisk{
+					DiskDriver: disk.DiskDriver,
+					Filename:   disk.Filename,
+					StorageID:  disk.StorageID,
+					Type:       disk.Type,
+				},
+				filename: fmt.Sprintf("%s-%s-retained-disk%d.qcow2", guest.Name, pool.ID, i),
+			})
+			i++
+		}
+	}
+	return disks, nil
+}
+
+func flattenRetainedGuestDisks(disks []retainedGuestDisk) []interface{} {
+	list := make([]interface{}, len(disks))
+	for i, disk := range disks {
+		list[i] = map[string]interface{}{
+			"storage_id": disk.disk.StorageID,
+			"filename":   disk.filename,
+		}
+	}
+	return list
+}
+
+// copyGuestPoolDisks copies the disks of the persistent guests in a pool so they outlive the pool.
+// Each guest is shut down before its disks are copied so the copies are consistent.
+func copyGuestPoolDisks(ctx context.Context, client *rest.Client, pool *rest.Pool, timeout time.Duration) error {
+	disks, err := guestPoolRetainedDisks(client, pool)
+	if err != nil {
+		return err
+	}
+	// check every target first so an existing file fails the destroy before anything is copied
+	for _, disk := range disks {
+		storage, err := client.GetStoragePool(disk.disk.StorageID)
+		if err != nil {
+			return err
+		}
+		if _, err := storage.DiskInfo(client, disk.filename); err == nil {
+			return fmt.Errorf("failed to retain %s: %s already exists in storage pool %s", disk.disk.Filename, disk.filename, storage.Name)
+		}
+	}
+	stopped := make(map[string]bool)
+	for _, disk := range disks {
+		if !stopped[disk.guest.Name] {
+			if err := shutdownGuest(ctx, client, disk.guest, remainingTimeout(ctx, timeout)); err != nil {
+				return fmt.Errorf("failed to retain the disks of %s: %w", disk.guest.Name, err)
+			}
+			stopped[disk.guest.Name] = true
+		}
+		if err := cloneDisk(ctx, client, disk.disk, disk.disk.StorageID, disk.filename, "full"); err != nil {
+			return fmt.Errorf("failed to retain %s: %w", disk.disk.Filename, err)
+		}
+	}
+	return nil
+}
+
+// shutdownGuest shuts a guest down and waits until it is off
+func shutdownGuest(ctx context.Context, client *rest.Client, guest *rest.Guest, timeout time.Duration) error {
+	if guestStopped(*guest) {
+		return nil
+	}
+	if err := guest.Shutdown(client); err != nil {
+		return fmt.Errorf("failed to shut down %s: %w", guest.Name, err)
+	}
+	if err := guest.WaitForGuestChange(ctx, client, timeout, guestStopped); err != nil {
+		return fmt.Errorf("waiting for %s to shut down: %w", guest.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package hiveio
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hive-io/hive-go-client/rest"
+)
+
+func testRetainPool(hive *fakeHive) *rest.Pool {
+	pool := &rest.Pool{ID: "pool1", Name: "desktops", GuestProfile: &rest.PoolGuestProfile{Persistent: true}}
+	hive.guests = []rest.Guest{
+		{Name: "DESK001", PoolID: pool.ID, GuestState: "ready", Disks: []rest.GuestDisk{
+			{Type: "Disk", StorageID: "disks", Filename: "DESK001.qcow2"},
+			{Type: "CD-ROM", StorageID: "isos", Filename: "tools.iso"},
+		}},
+		{Name: "DESK002", PoolID: pool.ID, GuestState: "off", Disks: []rest.GuestDisk{
+			{Type: "Disk", StorageID: "disks", Filename: "DESK002.qcow2"},
+		}},
+		{Name: "OTHER001", PoolID: "pool2", GuestState: "ready"},
+	}
+	return pool
+}
+
+func TestCopyGuestPoolDisks(t *testing.T) {
+	hive, client := newFakeHive(t)
+	pool := testRetainPool(hive)
+
+	if err := copyGuestPoolDisks(context.Background(), client, pool, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"disks/DESK001-pool1-retained-disk0.qcow2", "disks/DESK002-pool1-retained-disk0.qcow2"} {
+		if !hive.files[file] {
+			t.Errorf("expected %s to be retained, got %v", file, hive.files)
+		}
+	}
+	for _, guest := range hive.guests {
+		if guest.PoolID == pool.ID && guest.GuestState != "off" {
+			t.Errorf("expected %s to be shut down, it is %s", guest.Name, guest.GuestState)
+		}
+	}
+	shutdown := slices.Index(hive.requests, "POST /api/guest/DESK001/shutdown")
+	copied := slices.Index(hive.requests, "POST /api/template/convert")
+	if shutdown < 0 || copied < shutdown {
+		t.Errorf("expected DESK001 to be shut down before its disk is copied, requests %v", hive.requests)
+	}
+	if slices.Contains(hive.requests, "POST /api/guest/DESK002/shutdown") {
+		t.Errorf("DESK002 was already off and should not be shut down again")
+	}
+}
+
+func TestCopyGuestPoolDisksExistingTarget(t *testing.T) {
+	hive, client := newFakeHive(t)
+	pool := testRetainPool(hive)
+	hive.files["disks/DESK002-pool1-retained-disk0.qcow2"] = true
+
+	err := copyGuestPoolDisks(context.Background(), client, pool, 10*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected the existing copy to fail the destroy, got %v", err)
+	}
+	for _, request := range hive.requests {
+		if strings.HasSuffix(request, "/shutdown") || request == "POST /api/template/convert" {
+			t.Errorf("nothing should be shut down or copied, got %s", request)
+		}
+	}
+}
+
+func TestRemainingTimeout(t *testing.T) {
+	if got := remainingTimeout(context.Background(), time.Minute); got != time.Minute {
+		t.Errorf("expected the timeout without a deadline, got %s", got)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if got := remainingTimeout(ctx, time.Minute); got > time.Second || got <= 0 {
+		t.Errorf("expected at most the time left before the deadline, got %s", got)
+	}
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if got := remainingTimeout(expired, time.Minute); got <= 0 {
+		t.Errorf("expected a positive timeout after the deadline, got %s", got)
+	}
+}